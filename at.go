@@ -0,0 +1,102 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// At returns the string at index n of the expansion, i.e. Expand()[n],
+// without producing the strings before it.
+func (t *Tree) At(n int64) (string, error) {
+	return t.AtBig(big.NewInt(n))
+}
+
+// AtBig is like At for expansions with more strings than an int64 can
+// count, such as the indices returned by Decompose.
+func (t *Tree) AtBig(n *big.Int) (string, error) {
+	if n.Sign() < 0 || n.Cmp(t.Count()) >= 0 {
+		return "", fmt.Errorf("index %s out of range", n)
+	}
+	return t.Root.at(true, n), nil
+}
+
+// Slice returns the strings from index from up to, but not including,
+// index to of the expansion, i.e. Expand()[from:to].
+func (t *Tree) Slice(from, to int64) ([]string, error) {
+	if from < 0 || to < from || big.NewInt(to).Cmp(t.Count()) > 0 {
+		return nil, fmt.Errorf("slice bounds [%d:%d] out of range", from, to)
+	}
+
+	result := []string{}
+	for n := from; n < to; n++ {
+		result = append(result, t.Root.at(true, big.NewInt(n)))
+	}
+
+	return result, nil
+}
+
+// at follows the same rules as Expand: the phrases are expanded one
+// after another, so n is looked up in the phrase it falls into.
+func (l ListNode) at(root bool, n *big.Int) string {
+	if len(l.Phrases) == 0 {
+		return l.opts().OpenBrace + l.opts().CloseBrace
+	}
+
+	if len(l.Phrases) == 1 {
+		if root {
+			return l.Phrases[0].at(n)
+		}
+		if l.opts().TreatSingleAsOptional {
+			// the empty string comes last:
+			if n.Cmp(l.Phrases[0].Count()) == 0 {
+				return ""
+			}
+			return l.Phrases[0].at(n)
+		}
+		return l.opts().OpenBrace + l.Phrases[0].at(n) + l.opts().CloseBrace
+	}
+
+	n = new(big.Int).Set(n)
+	for _, phrase := range l.Phrases {
+		count := phrase.Count()
+		if n.Cmp(count) < 0 {
+			return phrase.at(n)
+		}
+		n.Sub(n, count)
+	}
+
+	panic("index out of range")
+}
+
+// at decodes n as a mixed-radix number, in which each part is a digit
+// and the count of the part is its base. The last part is the least
+// significant digit, since it changes fastest in the Cartesian product.
+func (p PhraseNode) at(n *big.Int) string {
+	n = new(big.Int).Set(n)
+	digits := make([]*big.Int, len(p.Parts))
+
+	for i := len(p.Parts) - 1; i >= 0; i-- {
+		digits[i] = new(big.Int)
+		n.QuoRem(n, countPart(p.Parts[i]), digits[i])
+	}
+
+	result := ""
+	for i, part := range p.Parts {
+		result += atPart(part, digits[i])
+	}
+
+	return result
+}
+
+func atPart(part Node, n *big.Int) string {
+	switch node := part.(type) {
+	case TextNode:
+		return node.Text
+	case ListNode:
+		return node.at(false, n)
+	case RangeNode:
+		return node.at(n.Int64())
+	default:
+		panic("unexpected node type")
+	}
+}
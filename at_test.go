@@ -0,0 +1,130 @@
+package braceexpansion
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/thomasheller/slicecmp"
+)
+
+func TestAt(t *testing.T) {
+	testAt(t, expandTests, parse)
+	testAt(t, quoteTests, parseQuotes)
+	testAt(t, rangeTests, parse)
+	testAt(t, charRangeTests, parse)
+	testAt(t, radixRangeTests, parse)
+	testAt(t, expandTestsCustom, parseCustom)
+}
+
+func testAt(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			for i, want := range test.output {
+				have, err := tree.At(int64(i))
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				if have != want {
+					t.Errorf("At(%d): want %q, have %q", i, want, have)
+				}
+			}
+
+			if _, err := tree.At(int64(len(test.output))); err == nil {
+				t.Errorf("At(%d): expected error, got none", len(test.output))
+			}
+
+			output, err := tree.Slice(0, int64(len(test.output)))
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !slicecmp.Equal(test.output, output) {
+				t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, test.output, output))
+			}
+		})
+	}
+}
+
+func TestAtLarge(t *testing.T) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	atTests := []struct {
+		n int64
+		s string
+	}{
+		{0, "aaaa00"},
+		{99, "aaaa99"},
+		{100, "aaab00"},
+		{45697599, "zzzz99"},
+	}
+
+	for _, at := range atTests {
+		s, err := tree.At(at.n)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if s != at.s {
+			t.Errorf("At(%d): want %q, have %q", at.n, at.s, s)
+		}
+	}
+
+	output, err := tree.Slice(198, 202)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := []string{"aaab98", "aaab99", "aaac00", "aaac01"}
+	if !slicecmp.Equal(expected, output) {
+		t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, expected, output))
+	}
+}
+
+func TestAtBig(t *testing.T) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}{a..z}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	// the last index, 26^14-1, does not fit into an int64:
+	last := new(big.Int).Sub(tree.Count(), big.NewInt(1))
+	if s, err := tree.AtBig(last); err != nil || s != "zzzzzzzzzzzzzz" {
+		t.Errorf("AtBig(%s): want %q, have %q (%v)", last, "zzzzzzzzzzzzzz", s, err)
+	}
+	if _, err := tree.AtBig(tree.Count()); err == nil {
+		t.Errorf("AtBig(%s): expected error, got none", tree.Count())
+	}
+
+	decompositions := tree.Decompose("zzzzzzzzzzzzzz")
+	if len(decompositions) != 1 || decompositions[0].Index.Cmp(last) != 0 {
+		t.Errorf("Expected index %s, got %v", last, decompositions)
+	}
+}
+
+func TestAtOutOfRange(t *testing.T) {
+	tree, err := parse("{a,b,c}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if _, err := tree.At(-1); err == nil {
+		t.Error("At(-1): expected error, got none")
+	}
+	if _, err := tree.At(3); err == nil {
+		t.Error("At(3): expected error, got none")
+	}
+	if _, err := tree.Slice(2, 1); err == nil {
+		t.Error("Slice(2, 1): expected error, got none")
+	}
+	if _, err := tree.Slice(0, 4); err == nil {
+		t.Error("Slice(0, 4): expected error, got none")
+	}
+	if output, err := tree.Slice(3, 3); err != nil || len(output) != 0 {
+		t.Errorf("Slice(3, 3): want empty slice, have %q, %v", output, err)
+	}
+}
@@ -1,17 +1,70 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+
 	be "github.com/thomasheller/braceexpansion"
 )
 
 func main() {
-	tree, err := be.New().Parse(os.Args[1])
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: be [-0] [-q] [--] pattern
+  -0	terminate each result with NUL instead of newline
+  -q	do not expand braces in single or double quotes`
+
+// run expands the pattern in args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	// only the flags themselves are read as flags, so a pattern like
+	// "-x{a,b}" needs no "--" in front of it:
+	nul, quotes := false, false
+flags:
+	for len(args) > 0 {
+		switch args[0] {
+		case "-0":
+			nul = true
+		case "-q":
+			quotes = true
+		case "--":
+			args = args[1:]
+			break flags
+		default:
+			break flags
+		}
+		args = args[1:]
+	}
+
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	opts := be.BashOpts()
+	opts.Quotes = quotes
+
+	tree, err := be.New().ParseCustom(args[0], opts)
 	if err != nil {
-		panic(err)
+		var pe *be.ParseError
+		if errors.As(err, &pe) {
+			fmt.Fprintln(stderr, pe.Pretty())
+		} else {
+			fmt.Fprintln(stderr, err)
+		}
+		return 1
 	}
-	for _, s := range tree.Expand() {
-		fmt.Println(s)
+
+	terminator := "\n"
+	if nul {
+		terminator = "\x00"
+	}
+
+	if err := tree.ExpandTo(stdout, terminator); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
 	}
+	return 0
 }
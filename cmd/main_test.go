@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	runTests := []struct {
+		args   []string
+		code   int
+		stdout string
+	}{
+		{[]string{"{a,b}{1,2}"}, 0, "a1\na2\nb1\nb2\n"},
+		{[]string{"-0", "{a,b}"}, 0, "a\x00b\x00"},
+		{[]string{"don't {a,b}"}, 0, "don't a\ndon't b\n"},
+		{[]string{"-q", "'{a,b}'{1,2}"}, 0, "{a,b}1\n{a,b}2\n"},
+		{[]string{"-q", "don't {a,b}"}, 1, ""},
+		{[]string{"{a,b"}, 1, ""},
+		{[]string{}, 2, ""},
+		{[]string{"-x{a,b}"}, 0, "-xa\n-xb\n"},
+		{[]string{"-0", "-{a,b}"}, 0, "-a\x00-b\x00"},
+		{[]string{"--", "-0"}, 0, "-0\n"},
+		{[]string{"-q"}, 2, ""},
+	}
+
+	for _, rt := range runTests {
+		var stdout, stderr bytes.Buffer
+		code := run(rt.args, &stdout, &stderr)
+		if code != rt.code || stdout.String() != rt.stdout {
+			t.Errorf("%q: want %d %q, have %d %q (%s)", rt.args, rt.code, rt.stdout, code, stdout.String(), stderr.String())
+		}
+	}
+}
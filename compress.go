@@ -0,0 +1,278 @@
+package braceexpansion
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// minRangeLen is the number of consecutive numbers it takes to write
+// them as a sequence expression instead of a list.
+const minRangeLen = 3
+
+// Compress builds a tree that expands to the given strings, though
+// without duplicates and not in their order, like hostlist does for
+// host names: it
+// factors out common prefixes and suffixes, and writes runs of numbers
+// like "web001" to "web120" as sequence expressions if opts allow for
+// ranges. Use Format to get the pattern, e.g. "web{001..120}".
+func (t *Tree) Compress(strs []string, opts ParseOpts) (*Tree, error) {
+	set := map[string]bool{}
+	unique := []string{}
+	for _, s := range strs {
+		if !set[s] {
+			set[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return t.compress(unique, opts, false)
+}
+
+// CompressOrdered is like Compress, but the tree expands to exactly the
+// given strings, in the same order and with duplicates.
+func (t *Tree) CompressOrdered(strs []string, opts ParseOpts) (*Tree, error) {
+	return t.compress(strs, opts, true)
+}
+
+func (t *Tree) compress(strs []string, opts ParseOpts, ordered bool) (*Tree, error) {
+	if len(strs) == 0 {
+		return nil, errors.New("no strings to compress")
+	}
+
+	t.opts = opts
+	c := &compressor{tree: t, ordered: ordered}
+	c.ranges = opts.Ranges && (opts.RangeRadix == 0 || opts.RangeRadix == 10) && opts.RangeWidth == 0
+
+	root := t.newListNode()
+	root.append(c.phrase(strs))
+	t.Root = &root
+
+	return t, nil
+}
+
+type compressor struct {
+	tree    *Tree
+	ordered bool // keep the order of the strings
+	ranges  bool // write runs of numbers as sequence expressions
+}
+
+// phrase returns a phrase that expands to strs, which has a common
+// prefix and suffix around a list of the rest.
+func (c *compressor) phrase(strs []string) PhraseNode {
+	pn := c.tree.newPhraseNode()
+	if len(strs) == 1 {
+		pn.append(c.tree.newTextNode(strs[0]))
+		return pn
+	}
+
+	prefix := commonPrefix(strs)
+	rest := make([]string, len(strs))
+	for i, s := range strs {
+		rest[i] = s[len(prefix):]
+	}
+	prefix, rest = c.keepNumbers(prefix, rest)
+	suffix := commonSuffix(rest)
+	for i, s := range rest {
+		rest[i] = s[:len(s)-len(suffix)]
+	}
+
+	if prefix != "" {
+		pn.append(c.tree.newTextNode(prefix))
+	}
+
+	alternatives := c.alternatives(rest)
+	if len(alternatives) == 1 {
+		for _, part := range alternatives[0].Parts {
+			pn.append(part)
+		}
+	} else {
+		ln := c.tree.newListNode()
+		for _, alternative := range alternatives {
+			ln.append(alternative)
+		}
+		pn.append(ln)
+	}
+
+	if suffix != "" {
+		pn.append(c.tree.newTextNode(suffix))
+	}
+
+	return pn
+}
+
+// keepNumbers moves the digits at the end of the prefix back to the
+// rest if that makes a sequence out of it, so "web01" to "web03" become
+// "web{01..03}" rather than "web0{1..3}".
+func (c *compressor) keepNumbers(prefix string, rest []string) (string, []string) {
+	i := len(prefix)
+	for i > 0 && prefix[i-1] >= '0' && prefix[i-1] <= '9' {
+		i--
+	}
+	if !c.ranges || i == len(prefix) {
+		return prefix, rest
+	}
+
+	numbers := make([]string, len(rest))
+	for j, s := range rest {
+		numbers[j] = prefix[i:] + s
+	}
+	sorted := numbers
+	if !c.ordered {
+		sorted = append([]string(nil), numbers...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return numberLess(sorted[i], sorted[j])
+		})
+	}
+	if _, n := c.rangeRun(sorted); n < minRangeLen || n < len(sorted) {
+		return prefix, rest
+	}
+
+	return prefix[:i], numbers
+}
+
+// alternatives returns the phrases of a list that expands to strs: runs
+// of numbers become sequence expressions, and strings that start with
+// the same rune are compressed together.
+func (c *compressor) alternatives(strs []string) []PhraseNode {
+	if !c.ordered {
+		strs = append([]string(nil), strs...)
+		sort.Slice(strs, func(i, j int) bool {
+			return numberLess(strs[i], strs[j])
+		})
+	}
+
+	alternatives := []PhraseNode{}
+	for i := 0; i < len(strs); {
+		if r, n := c.rangeRun(strs[i:]); n >= minRangeLen {
+			pn := c.tree.newPhraseNode()
+			pn.append(r)
+			alternatives = append(alternatives, pn)
+			i += n
+			continue
+		}
+
+		if strs[i] == "" {
+			alternatives = append(alternatives, c.tree.newPhraseNodeWithText(""))
+			i++
+			continue
+		}
+
+		first, _ := utf8.DecodeRuneInString(strs[i])
+		j := i + 1
+		for j < len(strs) && strs[j] != "" {
+			if r, _ := utf8.DecodeRuneInString(strs[j]); r != first {
+				break
+			}
+			j++
+		}
+		alternatives = append(alternatives, c.phrase(strs[i:j]))
+		i = j
+	}
+
+	return alternatives
+}
+
+// rangeRun returns a sequence expression for the run of consecutive
+// numbers at the start of strs, and the length of the run. The numbers
+// are either not padded, or all of the same width with a leading zero
+// in one of them, which is how bash pads sequences.
+func (c *compressor) rangeRun(strs []string) (RangeNode, int) {
+	if !c.ranges {
+		return RangeNode{}, 0
+	}
+
+	start, ok := number(strs[0])
+	if !ok {
+		return RangeNode{}, 0
+	}
+
+	natural, sameWidth := !zeroPadded(strs[0]), true
+	incr := int64(0)
+	n := 1
+	for ; n < len(strs); n++ {
+		m, ok := number(strs[n])
+		if !ok {
+			break
+		}
+		if n == 1 {
+			if m != start+1 && m != start-1 {
+				break
+			}
+			incr = m - start
+		} else if m != start+int64(n)*incr {
+			break
+		}
+		nat := natural && !zeroPadded(strs[n])
+		same := sameWidth && len(strs[n]) == len(strs[0])
+		if !nat && !same {
+			break
+		}
+		natural, sameWidth = nat, same
+	}
+
+	r := RangeNode{Start: start, End: start + int64(n-1)*incr}
+	if !natural {
+		r.Width = len(strs[0])
+	}
+	return r, n
+}
+
+// number parses s if it consists of decimal digits only.
+func number(s string) (int64, bool) {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// numberLess sorts numbers by their value and before other strings,
+// which are sorted as usual.
+func numberLess(a, b string) bool {
+	m, aok := number(a)
+	n, bok := number(b)
+	switch {
+	case aok && bok && m != n:
+		return m < n
+	case aok != bok:
+		return aok
+	default:
+		return a < b
+	}
+}
+
+// commonPrefix returns the longest prefix of all strings that ends at a
+// rune boundary.
+func commonPrefix(strs []string) string {
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		n := 0
+		for n < len(prefix) && n < len(s) && prefix[n] == s[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	n := len(prefix)
+	for n > 0 && n < len(strs[0]) && !utf8.RuneStart(strs[0][n]) {
+		n--
+	}
+	return prefix[:n]
+}
+
+// commonSuffix returns the longest suffix of all strings that starts at
+// a rune boundary.
+func commonSuffix(strs []string) string {
+	suffix := strs[0]
+	for _, s := range strs[1:] {
+		n := 0
+		for n < len(suffix) && n < len(s) && suffix[len(suffix)-1-n] == s[len(s)-1-n] {
+			n++
+		}
+		suffix = suffix[len(suffix)-n:]
+	}
+	for len(suffix) > 0 && !utf8.RuneStart(suffix[0]) {
+		suffix = suffix[1:]
+	}
+	return suffix
+}
@@ -0,0 +1,162 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/thomasheller/slicecmp"
+)
+
+func TestCompress(t *testing.T) {
+	// the empty string can only be printed in quotes:
+	opts := BashOpts()
+	opts.Quotes = true
+
+	hosts := []string{}
+	for i := 1; i <= 120; i++ {
+		hosts = append(hosts, fmt.Sprintf("web%03d", i))
+	}
+
+	compressTests := []struct {
+		input   []string
+		set     string
+		ordered string
+	}{
+		{hosts, "web{001..120}", "web{001..120}"},
+		{[]string{"web01", "web02", "web03", "db"}, "{db,web{01..03}}", "{web{01..03},db}"},
+		{[]string{"web010", "web020", "web030"}, "web0{1..3}0", "web0{1..3}0"},
+		{[]string{"a"}, "a", "a"},
+		{[]string{""}, "''", "''"},
+		{[]string{"", ""}, "''", "{,}"},
+		{[]string{"c", "a", "b"}, "{a,b,c}", "{c,a,b}"},
+		{[]string{"foo.txt", "bar.txt", "foo.txt"}, "{bar,foo}.txt", "{foo,bar,foo}.txt"},
+		{[]string{"x1", "x2", "x3", "x5"}, "x{{1..3},5}", "x{{1..3},5}"},
+		{[]string{"9", "10", "11"}, "{9..11}", "{9..11}"},
+		{[]string{"3", "2", "1"}, "{1..3}", "{3..1}"},
+		{[]string{"1", "2"}, "{1,2}", "{1,2}"},
+		{[]string{"08", "09", "10", "11"}, "{08..11}", "{08..11}"},
+		{[]string{"8", "09", "10"}, "{8,09,10}", "{8,09,10}"},
+		{[]string{"web", "web1", "web2", "web3"}, "web{{1..3},}", "web{,{1..3}}"},
+		{[]string{"a.b", "a.c", "x.b"}, "{a.{b,c},x.b}", "{a.{b,c},x.b}"},
+		{[]string{"a,b", "{c}"}, `{a\,b,\{c\}}`, `{a\,b,\{c\}}`},
+		{[]string{"äx", "äy", "öx"}, "{ä{x,y},öx}", "{ä{x,y},öx}"},
+	}
+
+	for _, ct := range compressTests {
+		t.Run(ct.set, func(t *testing.T) {
+			tree, err := New().Compress(ct.input, opts)
+			if err != nil {
+				t.Fatalf("Compress error: %v", err)
+			}
+			if have, err := tree.Format(); err != nil || have != ct.set {
+				t.Errorf("want %q, have %q (%v)", ct.set, have, err)
+			}
+
+			tree, err = New().CompressOrdered(ct.input, opts)
+			if err != nil {
+				t.Fatalf("CompressOrdered error: %v", err)
+			}
+			if have, err := tree.Format(); err != nil || have != ct.ordered {
+				t.Errorf("want ordered %q, have %q (%v)", ct.ordered, have, err)
+			}
+		})
+	}
+}
+
+func TestCompressNoRanges(t *testing.T) {
+	opts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	tree, err := New().Compress([]string{"x1", "x2", "x3", "y"}, opts)
+	if err != nil {
+		t.Fatalf("Compress error: %v", err)
+	}
+	if have, err := tree.Format(); err != nil || have != "(x(1,2,3),y)" {
+		t.Errorf("want %q, have %q (%v)", "(x(1,2,3),y)", have, err)
+	}
+}
+
+func TestCompressEmpty(t *testing.T) {
+	if _, err := New().Compress(nil, BashOpts()); err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
+// TestCompressProperties checks on random strings that the tree expands
+// to the input, and that so does its pattern.
+func TestCompressProperties(t *testing.T) {
+	opts := BashOpts()
+	opts.Quotes = true
+
+	r := rand.New(rand.NewSource(1))
+	randomString := func() string {
+		s := ""
+		for i := r.Intn(5); i > 0; i-- {
+			s += []string{"a", "b", "-", "0", "1", "9", "10", "{", ","}[r.Intn(9)]
+		}
+		return s
+	}
+
+	for i := 0; i < 1000; i++ {
+		input := make([]string, 1+r.Intn(20))
+		for j := range input {
+			input[j] = randomString()
+		}
+
+		ordered, err := New().CompressOrdered(input, opts)
+		if err != nil {
+			t.Fatalf("%q: CompressOrdered error: %v", input, err)
+		}
+		testCompressed(t, ordered, input, true)
+
+		set, err := New().Compress(input, opts)
+		if err != nil {
+			t.Fatalf("%q: Compress error: %v", input, err)
+		}
+		testCompressed(t, set, input, false)
+	}
+}
+
+// testCompressed checks that the tree and its pattern expand to the
+// input, in the same order if ordered is set.
+func testCompressed(t *testing.T, tree *Tree, input []string, ordered bool) {
+	t.Helper()
+
+	pattern, err := tree.Format()
+	if err != nil {
+		t.Fatalf("%q: Format error: %v", input, err)
+	}
+	reparsed, err := New().ParseCustom(pattern, tree.opts)
+	if err != nil {
+		t.Fatalf("%q: Parse error: %v", pattern, err)
+	}
+
+	have := tree.Expand()
+	if reparsed := reparsed.Expand(); !slicecmp.Equal(have, reparsed) {
+		t.Errorf("%q: Unexpected output:\n%s", pattern, slicecmp.Sprint([]string{"tree", "pattern"}, have, reparsed))
+	}
+
+	want := input
+	if !ordered {
+		want = sortedSet(input)
+		have = append([]string(nil), have...)
+		sort.Strings(have)
+	}
+	if !slicecmp.Equal(want, have) {
+		t.Errorf("%q: Unexpected output:\n%s", pattern, slicecmp.Sprint([]string{"want", "have"}, want, have))
+	}
+}
+
+func sortedSet(strs []string) []string {
+	set := map[string]bool{}
+	for _, s := range strs {
+		set[s] = true
+	}
+	sorted := []string{}
+	for s := range set {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
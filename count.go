@@ -0,0 +1,64 @@
+package braceexpansion
+
+import "math/big"
+
+// Count returns the number of strings Expand would return, without
+// expanding the tree, so huge patterns can be rejected cheaply.
+func (t *Tree) Count() *big.Int {
+	return t.Root.Count(true)
+}
+
+func (l ListNode) Count(root bool) *big.Int {
+	// empty brace expressions like "{}" are printed as regular text:
+	if len(l.Phrases) == 0 {
+		return big.NewInt(1)
+	}
+
+	// a single child adds an empty string if it is optional:
+	if len(l.Phrases) == 1 {
+		n := l.Phrases[0].Count()
+		if !root && l.opts().TreatSingleAsOptional {
+			n.Add(n, big.NewInt(1))
+		}
+		return n
+	}
+
+	n := big.NewInt(0)
+	for _, phrase := range l.Phrases {
+		n.Add(n, phrase.Count())
+	}
+	return n
+}
+
+func (p PhraseNode) Count() *big.Int {
+	if len(p.Parts) == 0 {
+		return big.NewInt(0)
+	}
+
+	n := big.NewInt(1)
+	for _, part := range p.Parts {
+		n.Mul(n, countPart(part))
+	}
+	return n
+}
+
+func countPart(part Node) *big.Int {
+	switch node := part.(type) {
+	case TextNode:
+		return node.Count()
+	case ListNode:
+		return node.Count(false)
+	case RangeNode:
+		return node.Count()
+	default:
+		panic("unexpected node type")
+	}
+}
+
+func (t TextNode) Count() *big.Int {
+	return big.NewInt(1)
+}
+
+func (r RangeNode) Count() *big.Int {
+	return big.NewInt(r.Len())
+}
@@ -0,0 +1,54 @@
+package braceexpansion
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	testCount(t, expandTests, parse)
+	testCount(t, quoteTests, parseQuotes)
+	testCount(t, rangeTests, parse)
+	testCount(t, charRangeTests, parse)
+	testCount(t, radixRangeTests, parse)
+	testCount(t, expandTestsCustom, parseCustom)
+}
+
+func testCount(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			if n := tree.Count(); n.Cmp(big.NewInt(int64(len(test.output)))) != 0 {
+				t.Errorf("want %d, have %v", len(test.output), n)
+			}
+		})
+	}
+}
+
+func TestCountLarge(t *testing.T) {
+	countTests := []struct {
+		input string
+		count string
+	}{
+		{"{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}", "45697600"},
+		{"{1..1000000000}{1..1000000000}{1..1000000000}", "1000000000000000000000000000"},
+		{"{a,b{1..5},c{x,y}}", "8"},
+		{"", "0"},
+	}
+
+	for _, ct := range countTests {
+		t.Run(ct.input, func(t *testing.T) {
+			tree, err := parse(ct.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if n := tree.Count().String(); n != ct.count {
+				t.Errorf("want %s, have %s", ct.count, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package braceexpansion
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Decomposition is one way the tree produces a string.
+type Decomposition struct {
+	// Choices are the choices of the lists and sequence expressions on
+	// the way, like MatchChoices returns them.
+	Choices []Choice
+
+	// Index is the index of the string in the expansion, so AtBig
+	// returns the string for it.
+	Index *big.Int
+}
+
+// Decompose returns every way the tree produces s, sorted by their
+// index in the expansion, or none if s is not part of it. It is the
+// inverse of AtBig: a string that appears more than once in the
+// expansion has a decomposition for each of its indices.
+func (t *Tree) Decompose(s string) []Decomposition {
+	m := t.newMatcher(s)
+
+	var result []Decomposition
+	m.paths(m.root, 0, len(s), nil, func(path []Choice) bool {
+		index, _ := m.index(m.root, path)
+		result = append(result, Decomposition{Choices: visible(path), Index: index})
+		return true
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Index.Cmp(result[j].Index) < 0
+	})
+	return result
+}
+
+// index returns the index in the expansion of n of the string the
+// choices produce, and the choices that are left for the nodes after n.
+// It follows the same rules as At.
+func (m *matcher) index(n *matchNode, choices []Choice) (*big.Int, []Choice) {
+	switch n.typ {
+	case NodeText:
+		return big.NewInt(0), choices
+	case NodeRange:
+		return big.NewInt(choices[0].Index), choices[1:]
+	case NodePhrase:
+		// the digits of a mixed-radix number, see PhraseNode.at:
+		index := big.NewInt(0)
+		for _, part := range n.parts {
+			var digit *big.Int
+			digit, choices = m.index(part, choices)
+			index.Mul(index, part.count())
+			index.Add(index, digit)
+		}
+		return index, choices
+	}
+
+	phrase := 0
+	if n.choice || len(n.phrases) > 1 {
+		phrase, choices = int(choices[0].Index), choices[1:]
+	}
+
+	// the phrases before the chosen one come first, and the empty string
+	// of an optional list comes last:
+	index := big.NewInt(0)
+	for _, pn := range n.node.(ListNode).Phrases[:phrase] {
+		index.Add(index, pn.Count())
+	}
+	if phrase == len(n.phrases) {
+		return index, choices
+	}
+
+	digit, choices := m.index(n.phrases[phrase], choices)
+	return index.Add(index, digit), choices
+}
+
+// count returns the number of strings the part n of a phrase expands
+// to.
+func (n *matchNode) count() *big.Int {
+	if n.typ == NodeText {
+		return big.NewInt(1)
+	}
+	return countPart(n.node)
+}
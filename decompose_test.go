@@ -0,0 +1,122 @@
+package braceexpansion
+
+import "testing"
+
+func TestDecompose(t *testing.T) {
+	testDecompose(t, expandTests, parse)
+	testDecompose(t, quoteTests, parseQuotes)
+	testDecompose(t, rangeTests, parse)
+	testDecompose(t, charRangeTests, parse)
+	testDecompose(t, radixRangeTests, parse)
+	testDecompose(t, expandTestsCustom, parseCustom)
+}
+
+// testDecompose checks that Decompose is the inverse of At: each string
+// decomposes into exactly the indices it has in the expansion.
+func testDecompose(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			indices := map[string][]int64{}
+			for i, s := range test.output {
+				indices[s] = append(indices[s], int64(i))
+			}
+
+			for s, want := range indices {
+				decompositions := tree.Decompose(s)
+				if len(decompositions) != len(want) {
+					t.Errorf("%q: want %d decompositions, have %d", s, len(want), len(decompositions))
+					continue
+				}
+				for i, d := range decompositions {
+					if !d.Index.IsInt64() || d.Index.Int64() != want[i] {
+						t.Errorf("%q: want index %d, have %s", s, want[i], d.Index)
+					}
+				}
+			}
+
+			if decompositions := tree.Decompose("\x00"); len(decompositions) != 0 {
+				t.Errorf("Expected no decompositions, got %d", len(decompositions))
+			}
+		})
+	}
+}
+
+func TestDecomposeChoices(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	decomposeTests := []struct {
+		input   string
+		opts    ParseOpts
+		s       string
+		choices []string
+	}{
+		{"web-{a,b}-{1..3}", BashOpts(), "web-b-2", []string{"4:1 10:1 "}},
+		{"{a,ab}{bc,c}", BashOpts(), "abc", []string{"0:0 6:0 ", "0:1 6:1 "}},
+		{"{x,x,y}", BashOpts(), "x", []string{"0:0 ", "0:1 "}},
+		{"x(a)(a)", customOpts, "xa", []string{"0:0 1:0 4:1 ", "0:0 1:1 4:0 "}},
+		{"{a,b}", BashOpts(), "c", nil},
+	}
+
+	for _, dt := range decomposeTests {
+		t.Run(dt.input+" "+dt.s, func(t *testing.T) {
+			tree, err := New().ParseCustom(dt.input, dt.opts)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			decompositions := tree.Decompose(dt.s)
+			if len(decompositions) != len(dt.choices) {
+				t.Fatalf("want %d decompositions, have %d", len(dt.choices), len(decompositions))
+			}
+			for i, d := range decompositions {
+				if have := choiceString(d.Choices); have != dt.choices[i] {
+					t.Errorf("want %q, have %q", dt.choices[i], have)
+				}
+				if s, err := tree.At(d.Index.Int64()); err != nil || s != dt.s {
+					t.Errorf("At(%s): want %q, have %q (%v)", d.Index, dt.s, s, err)
+				}
+			}
+		})
+	}
+}
+
+// TestDecomposeRoot checks a root with several phrases, which the
+// parser only makes with TreatRootAsList, so its choice is not returned.
+func TestDecomposeRoot(t *testing.T) {
+	tree, err := parse("a")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	phrase := func(s string) PhraseNode {
+		return PhraseNode{Parts: []Node{TextNode{Text: s}}}
+	}
+	root := tree.NewList(phrase("a"), phrase("b"), phrase("a"))
+	tree.Root = &root
+
+	for s, want := range map[string][]int64{"a": {0, 2}, "b": {1}} {
+		decompositions := tree.Decompose(s)
+		if len(decompositions) != len(want) {
+			t.Fatalf("%q: want %d decompositions, have %d", s, len(want), len(decompositions))
+		}
+		for i, d := range decompositions {
+			if len(d.Choices) != 0 {
+				t.Errorf("%q: want no choices, have %q", s, choiceString(d.Choices))
+			}
+			if !d.Index.IsInt64() || d.Index.Int64() != want[i] {
+				t.Errorf("%q: want index %d, have %s", s, want[i], d.Index)
+			}
+			if have, err := tree.AtBig(d.Index); err != nil || have != s {
+				t.Errorf("AtBig(%s): want %q, have %q (%v)", d.Index, s, have, err)
+			}
+		}
+	}
+
+	if choices, ok := tree.MatchChoices("b"); !ok || len(choices) != 0 {
+		t.Errorf("MatchChoices: want no choices, have %q (%t)", choiceString(choices), ok)
+	}
+}
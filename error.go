@@ -0,0 +1,107 @@
+package braceexpansion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError describes a syntax error in the input. Use errors.As to
+// get it from the error returned by Parse.
+type ParseError struct {
+	Input    string
+	Offset   int      // byte offset of the problem
+	Line     int      // starting at 1
+	Column   int      // in runes, starting at 1
+	Expected []string // kinds of tokens that would have been valid, if known
+	Found    string   // kind of the token at Offset
+	Msg      string
+}
+
+func newParseError(input string, offset int, expected []itemType, found itemType, msg string) *ParseError {
+	e := &ParseError{
+		Input:  input,
+		Offset: offset,
+		Line:   1 + strings.Count(input[:offset], "\n"),
+		Found:  found.String(),
+		Msg:    msg,
+	}
+	e.Column = 1 + utf8.RuneCountInString(input[e.lineStart():offset])
+	for _, typ := range expected {
+		e.Expected = append(e.Expected, typ.String())
+	}
+	return e
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Expected) > 0 {
+		return fmt.Sprintf("%d:%d: %s, expected %s", e.Line, e.Column, e.Msg, strings.Join(e.Expected, " or "))
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Pretty returns the error followed by the line of the input that
+// contains the problem and a caret pointing at it:
+//
+//	1:5: unexpected EOF in list, expected close
+//	{a,b
+//	    ^
+func (e *ParseError) Pretty() string {
+	start := e.lineStart()
+	end := strings.Index(e.Input[e.Offset:], "\n")
+	if end < 0 {
+		end = len(e.Input)
+	} else {
+		end += e.Offset
+	}
+
+	// keep tabs, so the caret lines up:
+	var caret bytes.Buffer
+	for _, r := range e.Input[start:e.Offset] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+
+	return e.Error() + "\n" + e.Input[start:end] + "\n" + caret.String()
+}
+
+// lineStart returns the offset of the line that contains the problem.
+func (e *ParseError) lineStart() int {
+	return strings.LastIndex(e.Input[:e.Offset], "\n") + 1
+}
+
+// ErrorList is returned by ParseAll if the input has syntax errors. It
+// holds all of them, ordered by offset.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// As finds the first ParseError for errors.As, which only follows
+// Unwrap to all errors from Go 1.20 on.
+func (l ErrorList) As(target interface{}) bool {
+	return len(l) > 0 && errors.As(l[0], target)
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As look at all of
+// them from Go 1.20 on.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
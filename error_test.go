@@ -0,0 +1,152 @@
+package braceexpansion
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type errorTest struct {
+	input    string
+	offset   int
+	line     int
+	column   int
+	expected string
+	found    string
+	err      string
+}
+
+var errorTests = []errorTest{
+	{"}", 0, 1, 1, "", "close", `1:1: unexpected close "}" in root`},
+	{"ab}cd", 2, 1, 3, "", "close", `1:3: unexpected close "}" in root`},
+	{"{abc,def", 8, 1, 9, "close", "EOF", `1:9: unexpected EOF in list, expected close`},
+	{"x{a,{b}", 7, 1, 8, "close", "EOF", `1:8: unexpected EOF in list, expected close`},
+	{"{a,'b}", 3, 1, 4, "", "error", `1:4: unterminated quote`},
+	{"äö}", 4, 1, 3, "", "close", `1:3: unexpected close "}" in root`},
+	{"{a,b}\n{c}}", 9, 2, 4, "", "close", `2:4: unexpected close "}" in root`},
+}
+
+func TestParseError(t *testing.T) {
+	for _, et := range errorTests {
+		t.Run(et.input, func(t *testing.T) {
+			_, err := parseQuotes(et.input)
+
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Expected ParseError, got %v", err)
+			}
+
+			if pe.Input != et.input || pe.Offset != et.offset || pe.Line != et.line || pe.Column != et.column {
+				t.Errorf("want %q at %d (%d:%d), have %q at %d (%d:%d)", et.input, et.offset, et.line, et.column, pe.Input, pe.Offset, pe.Line, pe.Column)
+			}
+			if strings.Join(pe.Expected, " ") != et.expected || pe.Found != et.found {
+				t.Errorf("want %q/%q, have %q/%q", et.expected, et.found, pe.Expected, pe.Found)
+			}
+			if pe.Error() != et.err {
+				t.Errorf("want %q, have %q", et.err, pe.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrorPretty(t *testing.T) {
+	prettyTests := []struct {
+		input  string
+		pretty string
+	}{
+		{"{abc,def", "1:9: unexpected EOF in list, expected close\n{abc,def\n        ^"},
+		{"{a,b}\n\t{c}}\nx", "2:5: unexpected close \"}\" in root\n\t{c}}\n\t   ^"},
+		{"α}", "1:2: unexpected close \"}\" in root\nα}\n ^"},
+	}
+
+	for _, pt := range prettyTests {
+		t.Run(pt.input, func(t *testing.T) {
+			_, err := parse(pt.input)
+
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Expected ParseError, got %v", err)
+			}
+			if pe.Pretty() != pt.pretty {
+				t.Errorf("want\n%s\nhave\n%s", pt.pretty, pe.Pretty())
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	parseAllTests := []struct {
+		input   string
+		offsets []int
+		output  []string
+	}{
+		{"{a,b}", nil, []string{"a", "b"}},
+		{"a}b}c{d,e", []int{1, 3, 9}, []string{"abcd", "abce"}},
+		{"{a,{b", []int{5}, []string{"a", "{b}"}},
+		{"{a,'b},c}", []int{3, 8}, []string{"a,c", "b,c"}},
+		{"x\"y}{1..3", []int{1, 3, 9}, []string{"xy{1..3}"}},
+	}
+
+	for _, pt := range parseAllTests {
+		t.Run(pt.input, func(t *testing.T) {
+			opts := BashOpts()
+			opts.Quotes = true
+			tree, err := New().ParseAll(pt.input, opts)
+			if tree == nil {
+				t.Fatalf("Expected a tree, got error %v", err)
+			}
+
+			var offsets []int
+			if err != nil {
+				var errs ErrorList
+				if !errors.As(err, &errs) {
+					t.Fatalf("Expected ErrorList, got %v", err)
+				}
+				for _, e := range errs {
+					offsets = append(offsets, e.Offset)
+				}
+			}
+			if !reflect.DeepEqual(offsets, pt.offsets) {
+				t.Errorf("want errors at %v, have %v (%v)", pt.offsets, offsets, err)
+			}
+
+			if output := tree.Expand(); !reflect.DeepEqual(output, pt.output) {
+				t.Errorf("want %q, have %q", pt.output, output)
+			}
+		})
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	_, err := New().ParseAll("a}b}", BashOpts())
+
+	want := `1:2: unexpected close "}" in root (and 1 more errors)`
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, have %v", want, err)
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Offset != 1 {
+		t.Errorf("Expected first ParseError, got %v", pe)
+	}
+
+	// As works without Unwrap, which errors.As ignores before Go 1.20:
+	pe = nil
+	if errs, ok := err.(ErrorList); !ok || !errs.As(&pe) || pe.Offset != 1 {
+		t.Errorf("Expected first ParseError from As, got %v", pe)
+	}
+	if (ErrorList{}).As(&pe) {
+		t.Error("Expected no ParseError in an empty list")
+	}
+}
+
+func TestParseAllLimit(t *testing.T) {
+	opts := BashOpts()
+	opts.MaxResults = 3
+
+	tree, err := New().ParseAll("}{a,b}{c,d", opts)
+	if !errors.Is(err, ErrLimitExceeded) || tree != nil {
+		t.Errorf("want %v, have %v, %v", ErrLimitExceeded, tree, err)
+	}
+}
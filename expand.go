@@ -1,13 +1,71 @@
 package braceexpansion
 
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
 func (t *Tree) Expand() []string {
 	return t.Root.Expand(true)
 }
 
+// ExpandContext is like Expand, but stops when ctx is done and returns
+// the strings produced so far along with ctx.Err().
+func (t *Tree) ExpandContext(ctx context.Context) ([]string, error) {
+	result := []string{}
+
+	it := t.Iter()
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// ExpandTo writes the expansion to w one string at a time, each
+// followed by terminator, e.g. "\n" or "\x00". The output is buffered.
+// ExpandTo stops at the first error returned by w, e.g. if w is a pipe
+// that was closed.
+func (t *Tree) ExpandTo(w io.Writer, terminator string) error {
+	return t.ExpandToContext(context.Background(), w, terminator)
+}
+
+// ExpandToContext is like ExpandTo, but stops when ctx is done. The
+// strings produced so far are flushed to w before returning ctx.Err().
+func (t *Tree) ExpandToContext(ctx context.Context, w io.Writer, terminator string) error {
+	bw := bufio.NewWriter(w)
+
+	it := t.Iter()
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		select {
+		case <-ctx.Done():
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		default:
+		}
+		if _, err := bw.WriteString(s); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(terminator); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
 func (l ListNode) Expand(root bool) []string {
 	// empty brace expressions like "{}" are printed as regular text:
 	if len(l.Phrases) == 0 {
-		return []string{l.Tree.opts.OpenBrace + l.Tree.opts.CloseBrace}
+		return []string{l.opts().OpenBrace + l.opts().CloseBrace}
 	}
 
 	// if ListNode has only one child, it may be treated as
@@ -17,13 +75,13 @@ func (l ListNode) Expand(root bool) []string {
 		if root {
 			return lines
 		} else {
-			if l.Tree.opts.TreatSingleAsOptional {
+			if l.opts().TreatSingleAsOptional {
 				lines = append(lines, "")
 				return lines
 			} else {
 				result := []string{}
 				for _, line := range lines {
-					result = append(result, l.Tree.opts.OpenBrace+line+l.Tree.opts.CloseBrace)
+					result = append(result, l.opts().OpenBrace+line+l.opts().CloseBrace)
 				}
 				return result
 			}
@@ -65,6 +123,10 @@ func (p *PhraseNode) expandPart(part Node) []string {
 		for _, line := range node.Expand(false) {
 			result = append(result, line)
 		}
+	case RangeNode:
+		for _, line := range node.Expand() {
+			result = append(result, line)
+		}
 	default:
 		panic("unexpected node type")
 	}
@@ -73,7 +135,15 @@ func (p *PhraseNode) expandPart(part Node) []string {
 }
 
 func (t TextNode) Expand() []string {
-	return []string{t.text}
+	return []string{t.Text}
+}
+
+func (r RangeNode) Expand() []string {
+	result := []string{}
+	for i := int64(0); i < r.Len(); i++ {
+		result = append(result, r.at(i))
+	}
+	return result
 }
 
 func Cartesian(sets [][]string) []string {
@@ -1,6 +1,9 @@
 package braceexpansion
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -41,6 +44,84 @@ var expandTests = []expandTest{
 	{"{,,,}", []string{"", "", "", ""}},
 	{"{a,{{{b}}}}", []string{"a", "{{{b}}}"}},
 	{"{a{1,2}b}", []string{"{a1b}", "{a2b}"}},
+	{`{a\,b,c}`, []string{"a,b", "c"}},
+	{`\{a,b\}`, []string{"{a,b}"}},
+	{`{a\}b,c}`, []string{"a}b", "c"}},
+	{`a\\{1,2}`, []string{`a\1`, `a\2`}},
+	{`{a\\,b}`, []string{`a\`, "b"}},
+	{`x\y`, []string{"xy"}},
+	{`{a,b}\`, []string{`a\`, `b\`}},
+	{`\{1..3\}`, []string{"{1..3}"}},
+	{"it's {a,b}", []string{"it's a", "it's b"}},
+	{`"{a,b}"`, []string{`"a"`, `"b"`}},
+	{"{1..5}", []string{"1", "2", "3", "4", "5"}},
+	{"{5..1}", []string{"5", "4", "3", "2", "1"}},
+	{"{-3..3}", []string{"-3", "-2", "-1", "0", "1", "2", "3"}},
+	{"{3..-2}", []string{"3", "2", "1", "0", "-1", "-2"}},
+	{"{+1..3}", []string{"1", "2", "3"}},
+	{"{1..1}", []string{"1"}},
+	{"x{1..3}y", []string{"x1y", "x2y", "x3y"}},
+	{"{a,{1..3}}", []string{"a", "1", "2", "3"}},
+	{"{1..3}{a,b}", []string{"1a", "1b", "2a", "2b", "3a", "3b"}},
+	{"{{1..3}}", []string{"{1}", "{2}", "{3}"}},
+	{"{1..3,x}", []string{"1..3", "x"}},
+	{"{1..a}", []string{"{1..a}"}},                                       // not a sequence expression
+	{"{1...3}", []string{"{1...3}"}},                                     // not a sequence expression
+	{"{--1..2}", []string{"{--1..2}"}},                                   // not a sequence expression
+	{"{1..}", []string{"{1..}"}},                                         // not a sequence expression
+	{"{99999999999999999999..1}", []string{"{99999999999999999999..1}"}}, // overflow
+}
+
+// quoteTests are parsed with ParseOpts.Quotes set.
+var quoteTests = []expandTest{
+	{`'{a,b}'`, []string{"{a,b}"}},
+	{`"{a,b}"x{1,2}`, []string{"{a,b}x1", "{a,b}x2"}},
+	{`a'{b,c}'{1,2}`, []string{"a{b,c}1", "a{b,c}2"}},
+	{`{'a,b',c}`, []string{"a,b", "c"}},
+	{`{"a}",b}`, []string{"a}", "b"}},
+	{`{a,"b,c"}d`, []string{"ad", "b,cd"}},
+	{`"a\"b{1,2}"`, []string{`a"b{1,2}`}},
+	{`'a\'`, []string{`a\`}},
+	{`"\a"`, []string{`\a`}},
+	{`"\\"{1,2}`, []string{`\1`, `\2`}},
+	{`\'{a,b}`, []string{"'a", "'b"}},
+	{`"{1..3}"`, []string{"{1..3}"}},
+	{`{a,b}'x'`, []string{"ax", "bx"}},
+	{`"it's"{1,2}`, []string{"it's1", "it's2"}},
+}
+
+var keepEscapesTests = []expandTest{
+	{`{a\,b,c}`, []string{`a\,b`, "c"}},
+	{`\{a,b\}`, []string{`\{a,b\}`}},
+	{`a\\{1,2}`, []string{`a\\1`, `a\\2`}},
+}
+
+var keepQuotesTests = []expandTest{
+	{`'{a,b}'`, []string{`'{a,b}'`}},
+	{`{'a,b',c}`, []string{`'a,b'`, "c"}},
+	{`"a\"b"{1,2}`, []string{`"a"b"1`, `"a"b"2`}},
+}
+
+// lenientTests were recorded with GNU bash 5.2, e.g. echo {a,b
+var lenientTests = []expandTest{
+	{"}", []string{"}"}},
+	{"}}", []string{"}}"}},
+	{"{{}", []string{"{{}"}},
+	{"{}}", []string{"{}}"}},
+	{"{,abc", []string{"{,abc"}},
+	{"{abc,def", []string{"{abc,def"}},
+	{"a}b{c,d}", []string{"a}bc", "a}bd"}},
+	{"{a,{b,c}", []string{"{a,b", "{a,c"}},
+	{"{a,b}}", []string{"a}", "b}"}},
+	{"}{a,b}{", []string{"}a{", "}b{"}},
+	{"{{a,b},c", []string{"{a,c", "{b,c"}},
+	{"{a,}b}", []string{"ab}", "b}"}},
+	{"{{a,b}", []string{"{a", "{b"}},
+	{"{a,b}{c", []string{"a{c", "b{c"}},
+	{"{1..3", []string{"{1..3"}},
+	{"{1..3}}", []string{"1}", "2}", "3}"}},
+	{`\{1..3}`, []string{"{1..3}"}},
+	{"{a,b}", []string{"a", "b"}},
 }
 
 var expandTestsCustom = []expandTest{
@@ -74,10 +155,168 @@ var expandTestsCustom = []expandTest{
 	{"(a(1,2)b)", []string{"a1b", "a2b", ""}},       // single-as-optional mode
 }
 
+// rangeTests were recorded with GNU bash 5.2, e.g. echo {1..20..5}
+var rangeTests = []expandTest{
+	{"{1..20..5}", []string{"1", "6", "11", "16"}},
+	{"{0..20..5}", []string{"0", "5", "10", "15", "20"}},
+	{"{1..10..3}", []string{"1", "4", "7", "10"}},
+	{"{10..1..3}", []string{"10", "7", "4", "1"}},
+	{"{-5..5..4}", []string{"-5", "-1", "3"}},
+	{"{1..1..5}", []string{"1"}},
+	{"{1..2..+1}", []string{"1", "2"}},
+	{"{1..10..03}", []string{"1", "4", "7", "10"}},
+	{"{1..5..0}", []string{"1", "2", "3", "4", "5"}},  // zero increment
+	{"{1..5..-0}", []string{"1", "2", "3", "4", "5"}}, // zero increment
+	{"{1..10..-3}", []string{"1", "4", "7", "10"}},    // negative increment
+	{"{10..1..-3}", []string{"10", "7", "4", "1"}},    // negative increment
+	{"{5..5..-1}", []string{"5"}},                     // negative increment
+	{"{1..10..9223372036854775807}", []string{"1"}},
+	{"x{1..7..3}y", []string{"x1y", "x4y", "x7y"}},
+	{"{1..3..1..2}", []string{"{1..3..1..2}"}},
+	{"{1..10..}", []string{"{1..10..}"}},
+	{"{1..5..a}", []string{"{1..5..a}"}},
+	{"{1..10..99999999999999999999}", []string{"{1..10..99999999999999999999}"}},
+	{"{1..10..-9223372036854775808}", []string{"{1..10..-9223372036854775808}"}},
+	{"{01..10}", []string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "10"}},
+	{"{1..010}", []string{"001", "002", "003", "004", "005", "006", "007", "008", "009", "010"}},
+	{"{001..5}", []string{"001", "002", "003", "004", "005"}},
+	{"{007..9}", []string{"007", "008", "009"}},
+	{"{10..08}", []string{"10", "09", "08"}},
+	{"{00..3}", []string{"00", "01", "02", "03"}},
+	{"{0..10..5}", []string{"0", "5", "10"}},
+	{"{08..11..2}", []string{"08", "10"}},
+	{"{-01..3}", []string{"-01", "000", "001", "002", "003"}},
+	{"{-1..03}", []string{"-1", "00", "01", "02", "03"}},
+	{"{-05..-3}", []string{"-05", "-04", "-03"}},
+	{"{-10..01}", []string{"-10", "-09", "-08", "-07", "-06", "-05", "-04", "-03", "-02", "-01", "000", "001"}},
+	{"{-0..02}", []string{"00", "01", "02"}},
+	{"{-0..2}", []string{"0", "1", "2"}},
+	{"{00..-1}", []string{"00", "-1"}},
+	{"{+01..3}", []string{"1", "2", "3"}},
+	{"{1..+03}", []string{"1", "2", "3"}},
+	{"web{098..101}", []string{"web098", "web099", "web100", "web101"}},
+	{"{a..f}", []string{"a", "b", "c", "d", "e", "f"}},
+	{"{Z..U}", []string{"Z", "Y", "X", "W", "V", "U"}},
+	{"{a..a}", []string{"a"}},
+	{"{X..c}", []string{"X", "Y", "Z", "[", "", "]", "^", "_", "`", "a", "b", "c"}},
+	{"{c..X}", []string{"c", "b", "a", "`", "_", "^", "]", "", "[", "Z", "Y", "X"}},
+	{"{Z..a}", []string{"Z", "[", "", "]", "^", "_", "`", "a"}},
+	{"{z..Z..2}", []string{"z", "x", "v", "t", "r", "p", "n", "l", "j", "h", "f", "d", "b", "`", "^", "", "Z"}},
+	{"{a..z..3}", []string{"a", "d", "g", "j", "m", "p", "s", "v", "y"}},
+	{"{z..a..-5}", []string{"z", "u", "p", "k", "f", "a"}},
+	{"{A..z..10}", []string{"A", "K", "U", "_", "i", "s"}},
+	{"{a..e..0}", []string{"a", "b", "c", "d", "e"}},
+	{"{a..c}{1..2}", []string{"a1", "a2", "b1", "b2", "c1", "c2"}},
+	{"{a..5}", []string{"{a..5}"}},
+	{"{5..a}", []string{"{5..a}"}},
+	{"{!..%}", []string{"{!..%}"}},
+	{"{aa..b}", []string{"{aa..b}"}},
+	{"{a..b..c}", []string{"{a..b..c}"}},
+}
+
+var charRangeTests = []expandTest{
+	{"{α..ε}", []string{"α", "β", "γ", "δ", "ε"}},
+	{"{ω..ψ}", []string{"ω", "ψ"}},
+	{"{α..ω..8}", []string{"α", "ι", "ρ", "ω"}},
+	{"{ä..ç}", []string{"ä", "å", "æ", "ç"}},
+	{"{A..𝐀}", []string{"{A..𝐀}"}}, // spans surrogate halves
+}
+
+var radixRangeTests = []expandTest{
+	{"{0x00..0x03}", []string{"0x00", "0x01", "0x02", "0x03"}},
+	{"{0x0a..0x0c}", []string{"0x0a", "0x0b", "0x0c"}},
+	{"{0X0A..0X0C}", []string{"0X0A", "0X0B", "0X0C"}},
+	{"{0xFE..0x101}", []string{"0xFE", "0xFF", "0x100", "0x101"}},
+	{"{0x10..0x0..8}", []string{"0x10", "0x8", "0x0"}},
+	{"{-0x2..0x2}", []string{"-0x2", "-0x1", "0x0", "0x1", "0x2"}},
+	{"{0o6..0o11}", []string{"0o6", "0o7", "0o10", "0o11"}},
+	{"{0b000..0b111}", []string{"0b000", "0b001", "0b010", "0b011", "0b100", "0b101", "0b110", "0b111"}},
+	{"{0b1..0b11}", []string{"0b1", "0b10", "0b11"}},
+	{"mac-{0x00..0x01}", []string{"mac-0x00", "mac-0x01"}},
+	{"{0x1..2}", []string{"{0x1..2}"}},
+	{"{0x1..0b1}", []string{"{0x1..0b1}"}},
+	{"{0xg..0xh}", []string{"{0xg..0xh}"}},
+	{"{0b12..0b13}", []string{"{0b12..0b13}"}},
+}
+
+var hexRangeTests = []expandTest{
+	{"{fe..101}", []string{"fe", "ff", "100", "101"}},
+	{"0x{0a..0c}", []string{"0x0a", "0x0b", "0x0c"}},
+	{"{A..C}", []string{"A", "B", "C"}},
+	{"{g..i}", []string{"g", "h", "i"}},
+	{"{0b1..0b11}", []string{"0b1", "0b10", "0b11"}},
+}
+
+var rangeWidthTests = []expandTest{
+	{"{1..3}", []string{"001", "002", "003"}},
+	{"{98..100}", []string{"098", "099", "100"}},
+	{"{01..02}", []string{"001", "002"}},
+	{"{-1..1}", []string{"-01", "000", "001"}},
+	{"{999..1001..2}", []string{"999", "1001"}},
+	{"{0x1..0x3}", []string{"0x001", "0x002", "0x003"}},
+}
+
 func TestExpand(t *testing.T) {
 	testExpand(t, expandTests, parse)
 }
 
+func TestExpandRange(t *testing.T) {
+	testExpand(t, rangeTests, parse)
+}
+
+func TestExpandCharRange(t *testing.T) {
+	testExpand(t, charRangeTests, parse)
+}
+
+func TestExpandRadixRange(t *testing.T) {
+	testExpand(t, radixRangeTests, parse)
+}
+
+func TestExpandHexRange(t *testing.T) {
+	testExpand(t, hexRangeTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeRadix = 16
+		return New().ParseCustom(input, opts)
+	})
+}
+
+func TestExpandRangeWidth(t *testing.T) {
+	testExpand(t, rangeWidthTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeWidth = 3
+		return New().ParseCustom(input, opts)
+	})
+}
+
+func TestExpandQuotes(t *testing.T) {
+	testExpand(t, quoteTests, parseQuotes)
+}
+
+func TestExpandKeepEscapes(t *testing.T) {
+	testExpand(t, keepEscapesTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.KeepEscapes = true
+		return New().ParseCustom(input, opts)
+	})
+}
+
+func TestExpandKeepQuotes(t *testing.T) {
+	testExpand(t, keepQuotesTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.Quotes = true
+		opts.KeepQuotes = true
+		return New().ParseCustom(input, opts)
+	})
+}
+
+func TestExpandLenient(t *testing.T) {
+	testExpand(t, lenientTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.Lenient = true
+		return New().ParseCustom(input, opts)
+	})
+}
+
 func TestExpandCustom(t *testing.T) {
 	testExpand(t, expandTestsCustom, parseCustom)
 }
@@ -100,6 +339,136 @@ func testExpand(t *testing.T, tests []expandTest, f parseFunc) {
 	}
 }
 
+func TestExpandTo(t *testing.T) {
+	tree, err := parse("{a,b}{1,2}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	expandToTests := []struct {
+		terminator string
+		output     string
+	}{
+		{"\n", "a1\na2\nb1\nb2\n"},
+		{"\x00", "a1\x00a2\x00b1\x00b2\x00"},
+		{"", "a1a2b1b2"},
+		{", ", "a1, a2, b1, b2, "},
+	}
+
+	for _, et := range expandToTests {
+		t.Run(fmt.Sprintf("%q", et.terminator), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tree.ExpandTo(&buf, et.terminator); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != et.output {
+				t.Errorf("want %q, have %q", et.output, buf.String())
+			}
+		})
+	}
+}
+
+// failingWriter accepts a limited number of bytes, like a pipe that
+// is closed by the reader.
+type failingWriter struct {
+	n int
+}
+
+var errClosed = errors.New("closed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errClosed
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestExpandToError(t *testing.T) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if err := tree.ExpandTo(&failingWriter{n: 10000}, "\n"); err != errClosed {
+		t.Errorf("want %v, have %v", errClosed, err)
+	}
+}
+
+// countdownContext is done after Done has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+var closed = make(chan struct{})
+
+func init() {
+	close(closed)
+}
+
+func (c *countdownContext) Done() <-chan struct{} {
+	if c.n == 0 {
+		return closed
+	}
+	c.n--
+	return nil
+}
+
+func (c *countdownContext) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestExpandContext(t *testing.T) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	output, err := tree.ExpandContext(&countdownContext{context.Background(), 3})
+	if err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+	expected := []string{"aaaa00", "aaaa01", "aaaa02"}
+	if !slicecmp.Equal(expected, output) {
+		t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, expected, output))
+	}
+
+	tree, err = parse("{a,b}{1,2}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	output, err = tree.ExpandContext(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = []string{"a1", "a2", "b1", "b2"}
+	if !slicecmp.Equal(expected, output) {
+		t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, expected, output))
+	}
+}
+
+func TestExpandToContext(t *testing.T) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = tree.ExpandToContext(&countdownContext{context.Background(), 2}, &buf, "\n")
+	if err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+	if buf.String() != "aaaa00\naaaa01\n" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+}
+
 func TestExpandTree(t *testing.T) {
 	tree := &Tree{}
 	ln := tree.newListNode()
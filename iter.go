@@ -0,0 +1,196 @@
+package braceexpansion
+
+import "strings"
+
+// Iterator produces the expansion of a Tree one string at a time, in
+// the same order as Expand. Instead of the results, it only keeps one
+// position per node of the tree.
+type Iterator struct {
+	root nodeIter
+}
+
+// Iter returns an Iterator over the expansion of the tree.
+func (t *Tree) Iter() *Iterator {
+	return &Iterator{root: newListIter(*t.Root, true)}
+}
+
+// Next returns the next string of the expansion, or false if there are
+// no more strings.
+func (it *Iterator) Next() (string, bool) {
+	return it.root.next()
+}
+
+// nodeIter iterates over the expansion of a single node and can be
+// started over, which PhraseNodes need for all parts but the first.
+type nodeIter interface {
+	next() (string, bool)
+	reset()
+}
+
+func newIter(n Node) nodeIter {
+	switch node := n.(type) {
+	case TextNode:
+		return &textIter{text: node.Text}
+	case ListNode:
+		return newListIter(node, false)
+	case RangeNode:
+		return &rangeIter{r: node}
+	default:
+		panic("unexpected node type")
+	}
+}
+
+type textIter struct {
+	text string
+	done bool
+}
+
+func (t *textIter) next() (string, bool) {
+	if t.done {
+		return "", false
+	}
+	t.done = true
+	return t.text, true
+}
+
+func (t *textIter) reset() {
+	t.done = false
+}
+
+type rangeIter struct {
+	r RangeNode
+	i int64
+}
+
+func (r *rangeIter) next() (string, bool) {
+	if r.i >= r.r.Len() {
+		return "", false
+	}
+	r.i++
+	return r.r.at(r.i - 1), true
+}
+
+func (r *rangeIter) reset() {
+	r.i = 0
+}
+
+// listIter follows the same rules as ListNode.Expand.
+type listIter struct {
+	phrases      []nodeIter
+	i            int
+	open, close  string // printed around a single phrase
+	optional     bool   // yields "" after a single phrase
+	optionalDone bool
+}
+
+func newListIter(l ListNode, root bool) nodeIter {
+	// empty brace expressions like "{}" are printed as regular text:
+	if len(l.Phrases) == 0 {
+		return &textIter{text: l.opts().OpenBrace + l.opts().CloseBrace}
+	}
+
+	li := &listIter{}
+	for _, phrase := range l.Phrases {
+		li.phrases = append(li.phrases, newPhraseIter(phrase))
+	}
+
+	if len(l.Phrases) == 1 && !root {
+		if l.opts().TreatSingleAsOptional {
+			li.optional = true
+		} else {
+			li.open, li.close = l.opts().OpenBrace, l.opts().CloseBrace
+		}
+	}
+
+	return li
+}
+
+func (l *listIter) next() (string, bool) {
+	for l.i < len(l.phrases) {
+		if s, ok := l.phrases[l.i].next(); ok {
+			return l.open + s + l.close, true
+		}
+		l.i++
+	}
+	if l.optional && !l.optionalDone {
+		l.optionalDone = true
+		return "", true
+	}
+	return "", false
+}
+
+func (l *listIter) reset() {
+	for _, phrase := range l.phrases {
+		phrase.reset()
+	}
+	l.i = 0
+	l.optionalDone = false
+}
+
+// phraseIter counts through the parts of a PhraseNode like an
+// odometer, the last part changing fastest, which is the order of
+// Cartesian.
+type phraseIter struct {
+	parts   []nodeIter
+	current []string
+	started bool
+	done    bool
+}
+
+func newPhraseIter(p PhraseNode) *phraseIter {
+	pi := &phraseIter{current: make([]string, len(p.Parts))}
+	for _, part := range p.Parts {
+		pi.parts = append(pi.parts, newIter(part))
+	}
+	return pi
+}
+
+func (p *phraseIter) next() (string, bool) {
+	if p.done {
+		return "", false
+	}
+
+	if !p.started {
+		p.started = true
+		if len(p.parts) == 0 {
+			p.done = true
+			return "", false
+		}
+		for i, part := range p.parts {
+			s, ok := part.next()
+			if !ok {
+				p.done = true
+				return "", false
+			}
+			p.current[i] = s
+		}
+		return strings.Join(p.current, ""), true
+	}
+
+	for i := len(p.parts) - 1; i >= 0; i-- {
+		s, ok := p.parts[i].next()
+		if !ok {
+			continue
+		}
+		p.current[i] = s
+
+		// start over with all parts after the one that moved on:
+		for j := i + 1; j < len(p.parts); j++ {
+			p.parts[j].reset()
+			p.current[j], _ = p.parts[j].next()
+		}
+
+		return strings.Join(p.current, ""), true
+	}
+
+	p.done = true
+	return "", false
+}
+
+func (p *phraseIter) reset() {
+	for _, part := range p.parts {
+		part.reset()
+	}
+	p.started = false
+	p.done = false
+}
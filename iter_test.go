@@ -0,0 +1,72 @@
+package braceexpansion
+
+import (
+	"testing"
+
+	"github.com/thomasheller/slicecmp"
+)
+
+func TestIter(t *testing.T) {
+	testIter(t, expandTests, parse)
+	testIter(t, quoteTests, parseQuotes)
+	testIter(t, rangeTests, parse)
+	testIter(t, charRangeTests, parse)
+	testIter(t, radixRangeTests, parse)
+	testIter(t, expandTestsCustom, parseCustom)
+}
+
+func testIter(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			output := []string{}
+			it := tree.Iter()
+			for s, ok := it.Next(); ok; s, ok = it.Next() {
+				output = append(output, s)
+			}
+
+			if !slicecmp.Equal(test.output, output) {
+				t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, test.output, output))
+			}
+
+			if _, ok := it.Next(); ok {
+				t.Error("Iterator not exhausted")
+			}
+		})
+	}
+}
+
+func TestIterLarge(t *testing.T) {
+	// 45 million strings, of which only the first few are produced:
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	output := []string{}
+	it := tree.Iter()
+	for i := 0; i < 12; i++ {
+		s, _ := it.Next()
+		output = append(output, s)
+	}
+
+	expected := []string{"aaaa00", "aaaa01", "aaaa02", "aaaa03", "aaaa04", "aaaa05", "aaaa06", "aaaa07", "aaaa08", "aaaa09", "aaaa10", "aaaa11"}
+
+	if !slicecmp.Equal(expected, output) {
+		t.Errorf("Unexpected output:\n%s", slicecmp.Sprint([]string{"want", "have"}, expected, output))
+	}
+}
+
+func TestIterEmpty(t *testing.T) {
+	tree, err := parse("")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if s, ok := tree.Iter().Next(); ok {
+		t.Errorf("Unexpected output: %q", s)
+	}
+}
@@ -1,12 +1,14 @@
 package braceexpansion
 
 import (
+	"fmt"
 	"strings"
 	"unicode/utf8"
 )
 
 type item struct {
 	typ itemType
+	pos int // byte offset in the input
 	val string
 }
 
@@ -19,19 +21,42 @@ const (
 	itemClose
 	itemSeparator
 	itemText
+	itemRange
 	itemEOF
 )
 
+var itemName = map[itemType]string{
+	itemError:     "error",
+	itemOpen:      "open",
+	itemClose:     "close",
+	itemSeparator: "separator",
+	itemText:      "text",
+	itemRange:     "range",
+	itemEOF:       "EOF",
+}
+
+func (i itemType) String() string {
+	s := itemName[i]
+	if s == "" {
+		return fmt.Sprintf("item%d", int(i))
+	}
+	return s
+}
+
 const eof = -1
 
 type stateFn func(*lexer) stateFn
 
+// lexer is driven by the parser: nextItem runs the state functions
+// until one of them emits an item.
 type lexer struct {
 	input string
 	start int
 	pos   int
 	width int
-	items chan item
+	state stateFn
+	items []item // emitted, but not yet returned by nextItem
+	head  int    // index of the next item to return
 	opts  ParseOpts
 }
 
@@ -57,41 +82,75 @@ func (l *lexer) backup() {
 }
 
 func (l *lexer) emit(t itemType) {
-	l.items <- item{t, l.input[l.start:l.pos]}
+	l.items = append(l.items, item{t, l.start, l.input[l.start:l.pos]})
 	l.start = l.pos
 }
 
+// errorf emits an error item at pos. Lexing goes on, so all errors in
+// the input can be reported.
+func (l *lexer) errorf(pos int, format string, args ...interface{}) {
+	l.items = append(l.items, item{itemError, pos, fmt.Sprintf(format, args...)})
+}
+
+// nextItem returns the next item, running the state functions as far
+// as needed. After EOF, it keeps returning EOF.
 func (l *lexer) nextItem() item {
-	item := <-l.items
-	return item
+	for l.head == len(l.items) && l.state != nil {
+		l.items, l.head = l.items[:0], 0
+		l.state = l.state(l)
+	}
+	if l.head == len(l.items) {
+		return item{itemEOF, len(l.input), ""}
+	}
+	l.head++
+	return l.items[l.head-1]
 }
 
-func (l *lexer) drain() {
-	for range l.items {
+// collect reads all items from the lexer, up to EOF.
+func (l *lexer) collect() []item {
+	items := []item{}
+	for {
+		item := l.nextItem()
+		items = append(items, item)
+		if item.typ == itemEOF {
+			return items
+		}
 	}
 }
 
 func lex(input string, opts ParseOpts) *lexer {
-	l := &lexer{
+	return &lexer{
 		input: input,
-		items: make(chan item),
+		state: lexText,
+		items: make([]item, 0, 2),
 		opts:  opts,
 	}
-	go l.run()
-	return l
-}
-
-func (l *lexer) run() {
-	for state := lexText; state != nil; {
-		state = state(l)
-	}
-	close(l.items)
 }
 
 // state functions
 
 func lexText(l *lexer) stateFn {
 	for {
+		if l.opts.Escape != "" && strings.HasPrefix(l.input[l.pos:], l.opts.Escape) {
+			// the escaped rune is part of the text, whatever it is:
+			l.pos += len(l.opts.Escape)
+			l.next()
+			continue
+		}
+		if l.opts.Quotes {
+			switch l.peek() {
+			case '\'':
+				return lexSingleQuote
+			case '"':
+				return lexDoubleQuote
+			}
+		}
+		if l.opts.Ranges && rangeLen(l.input[l.pos:], l.opts) > 0 {
+			if l.pos > l.start {
+				l.emit(itemText)
+			}
+			return lexRange
+		}
 		if strings.HasPrefix(l.input[l.pos:], l.opts.OpenBrace) {
 			if l.pos > l.start {
 				l.emit(itemText)
@@ -138,3 +197,50 @@ func lexSeparator(l *lexer) stateFn {
 	l.emit(itemSeparator)
 	return lexText
 }
+
+func lexRange(l *lexer) stateFn {
+	l.pos += rangeLen(l.input[l.pos:], l.opts)
+	l.emit(itemRange)
+	return lexText
+}
+
+// lexSingleQuote scans a quoted region, which becomes part of the
+// surrounding text. Like in bash, nothing can be escaped inside.
+func lexSingleQuote(l *lexer) stateFn {
+	quote := l.pos
+	l.next()
+	for {
+		switch l.next() {
+		case eof:
+			// go on with the quote as regular text:
+			l.errorf(quote, "unterminated quote")
+			l.pos = quote + 1
+			return lexText
+		case '\'':
+			return lexText
+		}
+	}
+}
+
+// lexDoubleQuote is like lexSingleQuote, but escapes are recognized, so
+// a double quote can be part of the quoted region.
+func lexDoubleQuote(l *lexer) stateFn {
+	quote := l.pos
+	l.next()
+	for {
+		if l.opts.Escape != "" && strings.HasPrefix(l.input[l.pos:], l.opts.Escape) {
+			l.pos += len(l.opts.Escape)
+			l.next()
+			continue
+		}
+		switch l.next() {
+		case eof:
+			// go on with the quote as regular text:
+			l.errorf(quote, "unterminated quote")
+			l.pos = quote + 1
+			return lexText
+		case '"':
+			return lexText
+		}
+	}
+}
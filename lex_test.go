@@ -5,25 +5,8 @@ import (
 	"testing"
 )
 
-var itemName = map[itemType]string{
-	itemError:     "error",
-	itemOpen:      "open",
-	itemClose:     "close",
-	itemSeparator: "separator",
-	itemText:      "text",
-	itemEOF:       "EOF",
-}
-
-func (i itemType) String() string {
-	s := itemName[i]
-	if s == "" {
-		return fmt.Sprintf("item%d", int(i))
-	}
-	return s
-}
-
 func (i item) String() string {
-	return fmt.Sprintf("%v:\"%s\"", i.typ, i.val)
+	return fmt.Sprintf("%v@%d:\"%s\"", i.typ, i.pos, i.val)
 }
 
 type lexTest struct {
@@ -33,90 +16,187 @@ type lexTest struct {
 
 var lexTests = []lexTest{
 	{"abc", []item{
-		item{itemText, "abc"},
-		item{itemEOF, ""},
+		item{itemText, 0, "abc"},
+		item{itemEOF, 3, ""},
 	}},
 	{"def", []item{
-		item{itemText, "def"},
-		item{itemEOF, ""},
+		item{itemText, 0, "def"},
+		item{itemEOF, 3, ""},
 	}},
 	{"{{", []item{
-		item{itemOpen, "{"},
-		item{itemOpen, "{"},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemOpen, 1, "{"},
+		item{itemEOF, 2, ""},
 	}},
 	{"{", []item{
-		item{itemOpen, "{"},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemEOF, 1, ""},
 	}},
 	{"}", []item{
-		item{itemClose, "}"},
-		item{itemEOF, ""},
+		item{itemClose, 0, "}"},
+		item{itemEOF, 1, ""},
 	}},
 	{",", []item{
-		item{itemSeparator, ","},
-		item{itemEOF, ""},
+		item{itemSeparator, 0, ","},
+		item{itemEOF, 1, ""},
 	}},
 	{"a,", []item{
-		item{itemText, "a"},
-		item{itemSeparator, ","},
-		item{itemEOF, ""},
+		item{itemText, 0, "a"},
+		item{itemSeparator, 1, ","},
+		item{itemEOF, 2, ""},
 	}},
 	{",a", []item{
-		item{itemSeparator, ","},
-		item{itemText, "a"},
-		item{itemEOF, ""},
+		item{itemSeparator, 0, ","},
+		item{itemText, 1, "a"},
+		item{itemEOF, 2, ""},
 	}},
 	{"{,", []item{
-		item{itemOpen, "{"},
-		item{itemSeparator, ","},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemSeparator, 1, ","},
+		item{itemEOF, 2, ""},
 	}},
 	{",,", []item{
-		item{itemSeparator, ","},
-		item{itemSeparator, ","},
-		item{itemEOF, ""},
+		item{itemSeparator, 0, ","},
+		item{itemSeparator, 1, ","},
+		item{itemEOF, 2, ""},
 	}},
 	{"a,b", []item{
-		item{itemText, "a"},
-		item{itemSeparator, ","},
-		item{itemText, "b"},
-		item{itemEOF, ""},
+		item{itemText, 0, "a"},
+		item{itemSeparator, 1, ","},
+		item{itemText, 2, "b"},
+		item{itemEOF, 3, ""},
 	}},
 	{"{a,b}", []item{
-		item{itemOpen, "{"},
-		item{itemText, "a"},
-		item{itemSeparator, ","},
-		item{itemText, "b"},
-		item{itemClose, "}"},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "a"},
+		item{itemSeparator, 2, ","},
+		item{itemText, 3, "b"},
+		item{itemClose, 4, "}"},
+		item{itemEOF, 5, ""},
 	}},
 	{"{a{1,2},b}", []item{
-		item{itemOpen, "{"},
-		item{itemText, "a"},
-		item{itemOpen, "{"},
-		item{itemText, "1"},
-		item{itemSeparator, ","},
-		item{itemText, "2"},
-		item{itemClose, "}"},
-		item{itemSeparator, ","},
-		item{itemText, "b"},
-		item{itemClose, "}"},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "a"},
+		item{itemOpen, 2, "{"},
+		item{itemText, 3, "1"},
+		item{itemSeparator, 4, ","},
+		item{itemText, 5, "2"},
+		item{itemClose, 6, "}"},
+		item{itemSeparator, 7, ","},
+		item{itemText, 8, "b"},
+		item{itemClose, 9, "}"},
+		item{itemEOF, 10, ""},
 	}},
 	{"{a,b}x{1,2}", []item{
-		item{itemOpen, "{"},
-		item{itemText, "a"},
-		item{itemSeparator, ","},
-		item{itemText, "b"},
-		item{itemClose, "}"},
-		item{itemText, "x"},
-		item{itemOpen, "{"},
-		item{itemText, "1"},
-		item{itemSeparator, ","},
-		item{itemText, "2"},
-		item{itemClose, "}"},
-		item{itemEOF, ""},
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "a"},
+		item{itemSeparator, 2, ","},
+		item{itemText, 3, "b"},
+		item{itemClose, 4, "}"},
+		item{itemText, 5, "x"},
+		item{itemOpen, 6, "{"},
+		item{itemText, 7, "1"},
+		item{itemSeparator, 8, ","},
+		item{itemText, 9, "2"},
+		item{itemClose, 10, "}"},
+		item{itemEOF, 11, ""},
+	}},
+	{"{1..3}", []item{
+		item{itemRange, 0, "{1..3}"},
+		item{itemEOF, 6, ""},
+	}},
+	{"a{-3..3}b", []item{
+		item{itemText, 0, "a"},
+		item{itemRange, 1, "{-3..3}"},
+		item{itemText, 8, "b"},
+		item{itemEOF, 9, ""},
+	}},
+	{"{1..a}", []item{
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "1..a"},
+		item{itemClose, 5, "}"},
+		item{itemEOF, 6, ""},
+	}},
+	{"{1..3,4}", []item{
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "1..3"},
+		item{itemSeparator, 5, ","},
+		item{itemText, 6, "4"},
+		item{itemClose, 7, "}"},
+		item{itemEOF, 8, ""},
+	}},
+	{`{a\,b,c}`, []item{
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, `a\,b`},
+		item{itemSeparator, 5, ","},
+		item{itemText, 6, "c"},
+		item{itemClose, 7, "}"},
+		item{itemEOF, 8, ""},
+	}},
+	{`\{a\}`, []item{
+		item{itemText, 0, `\{a\}`},
+		item{itemEOF, 5, ""},
+	}},
+	{`a\\{b}`, []item{
+		item{itemText, 0, `a\\`},
+		item{itemOpen, 3, "{"},
+		item{itemText, 4, "b"},
+		item{itemClose, 5, "}"},
+		item{itemEOF, 6, ""},
+	}},
+	{`\{1..3\}`, []item{
+		item{itemText, 0, `\{1..3\}`},
+		item{itemEOF, 8, ""},
+	}},
+	{`a\`, []item{
+		item{itemText, 0, `a\`},
+		item{itemEOF, 2, ""},
+	}},
+	{`'{a,b}'`, []item{
+		item{itemText, 0, `'{a,b}'`},
+		item{itemEOF, 7, ""},
+	}},
+	{`{'a,b',c}`, []item{
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, `'a,b'`},
+		item{itemSeparator, 6, ","},
+		item{itemText, 7, "c"},
+		item{itemClose, 8, "}"},
+		item{itemEOF, 9, ""},
+	}},
+	{`a"{b\"}"{1,2}`, []item{
+		item{itemText, 0, `a"{b\"}"`},
+		item{itemOpen, 8, "{"},
+		item{itemText, 9, "1"},
+		item{itemSeparator, 10, ","},
+		item{itemText, 11, "2"},
+		item{itemClose, 12, "}"},
+		item{itemEOF, 13, ""},
+	}},
+	{`'a\'{1,2}`, []item{
+		item{itemText, 0, `'a\'`},
+		item{itemOpen, 4, "{"},
+		item{itemText, 5, "1"},
+		item{itemSeparator, 6, ","},
+		item{itemText, 7, "2"},
+		item{itemClose, 8, "}"},
+		item{itemEOF, 9, ""},
+	}},
+	{`\'{a}`, []item{
+		item{itemText, 0, `\'`},
+		item{itemOpen, 2, "{"},
+		item{itemText, 3, "a"},
+		item{itemClose, 4, "}"},
+		item{itemEOF, 5, ""},
+	}},
+	{`{a,'b}`, []item{
+		item{itemOpen, 0, "{"},
+		item{itemText, 1, "a"},
+		item{itemSeparator, 2, ","},
+		item{itemError, 3, "unterminated quote"},
+	}},
+	{`"a\"`, []item{
+		item{itemError, 0, "unterminated quote"},
 	}},
 }
 
@@ -132,7 +212,9 @@ func TestLex(t *testing.T) {
 }
 
 func collect(input string) (items []item) {
-	l := lex(input, ParseOpts{OpenBrace: "{", CloseBrace: "}", Separator: ","})
+	opts := BashOpts()
+	opts.Quotes = true
+	l := lex(input, opts)
 	for {
 		item := l.nextItem()
 		items = append(items, item)
@@ -151,6 +233,9 @@ func equal(a, b []item) bool {
 		if a[i].typ != b[i].typ {
 			return false
 		}
+		if a[i].pos != b[i].pos {
+			return false
+		}
 		if a[i].val != b[i].val {
 			return false
 		}
@@ -0,0 +1,206 @@
+package braceexpansion
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// ErrLimitExceeded matches every LimitError when using errors.Is.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limit identifies one of the limits in ParseOpts.
+type Limit int
+
+const (
+	LimitResults Limit = iota
+	LimitDepth
+	LimitLength
+	LimitBytes
+)
+
+var limitName = map[Limit]string{
+	LimitResults: "results",
+	LimitDepth:   "depth",
+	LimitLength:  "length",
+	LimitBytes:   "bytes",
+}
+
+func (l Limit) String() string {
+	return limitName[l]
+}
+
+// LimitError is returned when parsing a pattern that exceeds one of the
+// limits in ParseOpts.
+type LimitError struct {
+	Limit Limit
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// checkLimits computes the size of the expansion from the tree, so no
+// strings have to be produced.
+func (t *Tree) checkLimits() {
+	if t.opts.MaxResults == 0 && t.opts.MaxLength == 0 && t.opts.MaxBytes == 0 {
+		return
+	}
+
+	s := t.Root.size(true)
+
+	if t.opts.MaxResults > 0 && s.count.Cmp(big.NewInt(int64(t.opts.MaxResults))) > 0 {
+		t.error(&LimitError{Limit: LimitResults, Max: t.opts.MaxResults})
+	}
+	if t.opts.MaxLength > 0 && s.maxLen > t.opts.MaxLength {
+		t.error(&LimitError{Limit: LimitLength, Max: t.opts.MaxLength})
+	}
+	if t.opts.MaxBytes > 0 && s.bytes.Cmp(big.NewInt(int64(t.opts.MaxBytes))) > 0 {
+		t.error(&LimitError{Limit: LimitBytes, Max: t.opts.MaxBytes})
+	}
+}
+
+// size describes the expansion of a node.
+type size struct {
+	count  *big.Int // number of strings
+	bytes  *big.Int // length of all strings
+	maxLen int      // length of the longest string
+}
+
+func (l ListNode) size(root bool) size {
+	if len(l.Phrases) == 0 {
+		braces := len(l.opts().OpenBrace + l.opts().CloseBrace)
+		return size{big.NewInt(1), big.NewInt(int64(braces)), braces}
+	}
+
+	if len(l.Phrases) == 1 {
+		s := l.Phrases[0].size()
+		switch {
+		case root:
+		case l.opts().TreatSingleAsOptional:
+			s.count.Add(s.count, big.NewInt(1))
+		default:
+			braces := len(l.opts().OpenBrace + l.opts().CloseBrace)
+			s.bytes.Add(s.bytes, new(big.Int).Mul(s.count, big.NewInt(int64(braces))))
+			s.maxLen += braces
+		}
+		return s
+	}
+
+	s := size{big.NewInt(0), big.NewInt(0), 0}
+	for _, phrase := range l.Phrases {
+		ps := phrase.size()
+		s.count.Add(s.count, ps.count)
+		s.bytes.Add(s.bytes, ps.bytes)
+		if ps.maxLen > s.maxLen {
+			s.maxLen = ps.maxLen
+		}
+	}
+	return s
+}
+
+// size of a phrase: every string of a part is combined with every
+// string of the other parts, so it occurs count/part.count times.
+func (p PhraseNode) size() size {
+	if len(p.Parts) == 0 {
+		return size{big.NewInt(0), big.NewInt(0), 0}
+	}
+
+	sizes := []size{}
+	s := size{big.NewInt(1), big.NewInt(0), 0}
+	for _, part := range p.Parts {
+		ps := sizePart(part)
+		sizes = append(sizes, ps)
+		s.count.Mul(s.count, ps.count)
+		s.maxLen += ps.maxLen
+	}
+
+	if s.count.Sign() == 0 {
+		return size{s.count, s.bytes, 0}
+	}
+
+	for _, ps := range sizes {
+		n := new(big.Int).Quo(s.count, ps.count)
+		s.bytes.Add(s.bytes, n.Mul(n, ps.bytes))
+	}
+
+	return s
+}
+
+func sizePart(part Node) size {
+	switch node := part.(type) {
+	case TextNode:
+		return size{big.NewInt(1), big.NewInt(int64(len(node.Text))), len(node.Text)}
+	case ListNode:
+		return node.size(false)
+	case RangeNode:
+		return node.size()
+	default:
+		panic("unexpected node type")
+	}
+}
+
+// size of a sequence: its elements are counted in groups of the same
+// length, which only changes at the powers of the radix, or for
+// characters where their UTF-8 encoding gets longer.
+func (r RangeNode) size() size {
+	// lo and hi are the smallest and the largest element:
+	lo, hi := r.Start, int64(uint64(r.Start)+uint64(r.Len()-1)*r.step())
+	if r.End < r.Start {
+		lo, hi = int64(uint64(r.Start)-uint64(r.Len()-1)*r.step()), r.Start
+	}
+
+	starts := []int64{lo, 0}
+	if r.Chars {
+		starts = append(starts, '\\', '\\'+1, 0x80, 0x800, 0x10000)
+	} else {
+		radix := int64(r.Radix)
+		if radix == 0 {
+			radix = 10
+		}
+		for p := radix; ; p *= radix {
+			starts = append(starts, p, -p+1)
+			if p > math.MaxInt64/radix {
+				break
+			}
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	s := size{big.NewInt(r.Len()), big.NewInt(0), 0}
+	for i, start := range starts {
+		if start < lo || start > hi || i > 0 && start == starts[i-1] {
+			continue
+		}
+		end := hi
+		for _, next := range starts[i+1:] {
+			if next > start {
+				if next-1 < end {
+					end = next - 1
+				}
+				break
+			}
+		}
+
+		// the elements lo + j*step in [start, end]:
+		first := (uint64(start) - uint64(lo) + r.step() - 1) / r.step()
+		last := (uint64(end) - uint64(lo)) / r.step()
+		if last < first {
+			continue
+		}
+		n := len(r.format(start))
+		count := new(big.Int).SetUint64(last - first + 1)
+		s.bytes.Add(s.bytes, count.Mul(count, big.NewInt(int64(n))))
+		if n > s.maxLen {
+			s.maxLen = n
+		}
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+package braceexpansion
+
+import (
+	"errors"
+	"testing"
+)
+
+type limitTest struct {
+	input string
+	opts  ParseOpts
+	limit Limit
+	ok    bool
+}
+
+func limitOpts(results, depth, length, bytes int) ParseOpts {
+	opts := BashOpts()
+	opts.MaxResults = results
+	opts.MaxDepth = depth
+	opts.MaxLength = length
+	opts.MaxBytes = bytes
+	return opts
+}
+
+var limitTests = []limitTest{
+	{"{a,b}{1,2}", limitOpts(4, 0, 0, 0), 0, true},
+	{"{a,b}{1,2,3}", limitOpts(4, 0, 0, 0), LimitResults, false},
+	{"{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}", limitOpts(1000000, 0, 0, 0), LimitResults, false},
+	{"{a,{b,c}}", limitOpts(0, 2, 0, 0), 0, true},
+	{"{a,{b,{c,d}}}", limitOpts(0, 2, 0, 0), LimitDepth, false},
+	{"{a}{b}{c}", limitOpts(0, 1, 0, 0), 0, true},
+	{"x{abc,de}y", limitOpts(0, 0, 5, 0), 0, true},
+	{"x{abc,def}yz", limitOpts(0, 0, 5, 0), LimitLength, false},
+	{"{abc}", limitOpts(0, 0, 4, 0), LimitLength, false},
+	{"{1..100}", limitOpts(0, 0, 3, 0), 0, true},
+	{"{-100..1}", limitOpts(0, 0, 3, 0), LimitLength, false},
+	{"{a,bc}{1,2}", limitOpts(0, 0, 0, 10), 0, true},
+	{"{a,bc}{1,2}", limitOpts(0, 0, 0, 9), LimitBytes, false},
+	{"{1..1000}", limitOpts(0, 0, 0, 2893), 0, true},
+	{"{1..1000}", limitOpts(0, 0, 0, 2892), LimitBytes, false},
+}
+
+func TestLimits(t *testing.T) {
+	for _, lt := range limitTests {
+		t.Run(lt.input, func(t *testing.T) {
+			_, err := New().ParseCustom(lt.input, lt.opts)
+
+			switch {
+			case err == nil && !lt.ok:
+				t.Error("Expected error, got none")
+			case err != nil && lt.ok:
+				t.Errorf("Unexpected error: %v", err)
+			case err != nil:
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Errorf("Expected ErrLimitExceeded, got %v", err)
+				}
+				var le *LimitError
+				if !errors.As(err, &le) || le.Limit != lt.limit {
+					t.Errorf("Expected %v limit, got %v", lt.limit, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	testSize(t, expandTests, parse)
+	testSize(t, quoteTests, parseQuotes)
+	testSize(t, rangeTests, parse)
+	testSize(t, charRangeTests, parse)
+	testSize(t, radixRangeTests, parse)
+	testSize(t, hexRangeTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeRadix = 16
+		return New().ParseCustom(input, opts)
+	})
+	testSize(t, rangeWidthTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeWidth = 3
+		return New().ParseCustom(input, opts)
+	})
+	testSize(t, expandTestsCustom, parseCustom)
+}
+
+func testSize(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			bytes, maxLen := 0, 0
+			for _, s := range test.output {
+				bytes += len(s)
+				if len(s) > maxLen {
+					maxLen = len(s)
+				}
+			}
+
+			s := tree.Root.size(true)
+			if s.count.Int64() != int64(len(test.output)) || s.maxLen != maxLen {
+				t.Errorf("want %d/%d, have %v/%d", len(test.output), maxLen, s.count, s.maxLen)
+			}
+
+			if s.bytes.Int64() != int64(bytes) {
+				t.Errorf("want %d bytes, have %v", bytes, s.bytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package braceexpansion
+
+import (
+	"sort"
+	"strings"
+)
+
+// Choice is the alternative a list chose to produce a string, or the
+// element of a sequence expression it came from.
+type Choice struct {
+	Node Node // ListNode or RangeNode
+
+	// Index is the index of the phrase of a ListNode, or of the element
+	// of a RangeNode. For a list that is optional, since it has a single
+	// phrase and TreatSingleAsOptional is set, the empty string has the
+	// index 1.
+	Index int64
+}
+
+// Match reports whether s is one of the strings the tree expands to,
+// without expanding it.
+func (t *Tree) Match(s string) bool {
+	m := t.newMatcher(s)
+	return m.matches(m.root, 0, len(s))
+}
+
+// MatchChoices is like Match, but also returns the choices that
+// produce s, one for each list on the way and each sequence expression.
+// The root list is only included if TreatRootAsList is set, and lists
+// like "{}", which are printed as text, never are. If there is more
+// than one way to produce s, MatchChoices returns any of them.
+func (t *Tree) MatchChoices(s string) ([]Choice, bool) {
+	m := t.newMatcher(s)
+
+	var choices []Choice
+	done := m.paths(m.root, 0, len(s), nil, func(path []Choice) bool {
+		choices = visible(path)
+		return false
+	})
+	return choices, !done
+}
+
+// matcher finds the ways the tree can produce s like an NFA: for each
+// node and position in s, it computes the positions at which the node
+// can end, remembering them to avoid doing the same work twice.
+type matcher struct {
+	s    string
+	root *matchNode
+	ends map[matchKey][]int
+}
+
+type matchKey struct {
+	n   *matchNode
+	i   int // parts of a phrase from index i on, -1 for the whole node
+	pos int
+}
+
+// matchNode follows the same rules as ListNode.Expand.
+type matchNode struct {
+	typ     NodeType
+	node    Node         // for choices
+	text    string       // TextNode, or a list like "{}"
+	r       RangeNode    // RangeNode
+	maxLen  int          // of an element of r
+	phrases []*matchNode // ListNode
+	parts   []*matchNode // PhraseNode
+
+	open, close string // printed around a single phrase
+	optional    bool   // matches "" after a single phrase
+	choice      bool   // the choice of the list is returned
+}
+
+func (t *Tree) newMatcher(s string) *matcher {
+	return &matcher{
+		s:    s,
+		root: newMatchNode(*t.Root, true),
+		ends: map[matchKey][]int{},
+	}
+}
+
+func newMatchNode(n Node, root bool) *matchNode {
+	switch node := n.(type) {
+	case TextNode:
+		return &matchNode{typ: NodeText, text: node.Text}
+	case RangeNode:
+		maxLen := len(node.format(node.Start))
+		if l := len(node.format(node.End)); l > maxLen {
+			maxLen = l
+		}
+		return &matchNode{typ: NodeRange, node: node, r: node, maxLen: maxLen}
+	case PhraseNode:
+		mn := &matchNode{typ: NodePhrase}
+		for _, part := range node.Parts {
+			mn.parts = append(mn.parts, newMatchNode(part, false))
+		}
+		return mn
+	case ListNode:
+		opts := node.opts()
+
+		// empty brace expressions like "{}" are printed as regular text:
+		if len(node.Phrases) == 0 {
+			return &matchNode{typ: NodeText, text: opts.OpenBrace + opts.CloseBrace}
+		}
+
+		mn := &matchNode{typ: NodeList, node: node, choice: !root || opts.TreatRootAsList}
+		for _, phrase := range node.Phrases {
+			mn.phrases = append(mn.phrases, newMatchNode(phrase, false))
+		}
+		if len(node.Phrases) == 1 && !root {
+			if opts.TreatSingleAsOptional {
+				mn.optional = true
+			} else {
+				mn.open, mn.close = opts.OpenBrace, opts.CloseBrace
+			}
+		}
+		return mn
+	default:
+		panic("unexpected node type")
+	}
+}
+
+// matches reports whether n can produce s[pos:end].
+func (m *matcher) matches(n *matchNode, pos, end int) bool {
+	return contains(m.endsOf(n, -1, pos), end)
+}
+
+// endsOf returns the sorted positions at which n can end if it starts
+// at pos, or with i >= 0 the parts of the phrase n from index i on.
+func (m *matcher) endsOf(n *matchNode, i, pos int) []int {
+	key := matchKey{n, i, pos}
+	if ends, ok := m.ends[key]; ok {
+		return ends
+	}
+
+	ends := []int{}
+	switch {
+	case n.typ == NodePhrase && i < 0:
+		// a phrase without parts expands to nothing at all:
+		if len(n.parts) > 0 {
+			ends = m.endsOf(n, 0, pos)
+		}
+	case n.typ == NodePhrase && i == len(n.parts):
+		ends = []int{pos}
+	case n.typ == NodePhrase:
+		for _, e := range m.endsOf(n.parts[i], -1, pos) {
+			ends = append(ends, m.endsOf(n, i+1, e)...)
+		}
+	case n.typ == NodeText:
+		if strings.HasPrefix(m.s[pos:], n.text) {
+			ends = append(ends, pos+len(n.text))
+		}
+	case n.typ == NodeRange:
+		// an element may be empty, see RangeNode.format:
+		for e := pos; e <= len(m.s) && e-pos <= n.maxLen; e++ {
+			if _, ok := n.r.index(m.s[pos:e]); ok {
+				ends = append(ends, e)
+			}
+		}
+	case n.typ == NodeList:
+		if n.optional {
+			ends = append(ends, pos)
+		}
+		if !strings.HasPrefix(m.s[pos:], n.open) {
+			break
+		}
+		for _, phrase := range n.phrases {
+			for _, e := range m.endsOf(phrase, -1, pos+len(n.open)) {
+				if strings.HasPrefix(m.s[e:], n.close) {
+					ends = append(ends, e+len(n.close))
+				}
+			}
+		}
+	}
+
+	ends = unique(ends)
+	m.ends[key] = ends
+	return ends
+}
+
+// paths calls yield with each way n can produce s[pos:end], following
+// the choices in path so far, until yield returns false. It returns
+// false if it was stopped.
+func (m *matcher) paths(n *matchNode, pos, end int, path []Choice, yield func([]Choice) bool) bool {
+	if !m.matches(n, pos, end) {
+		return true
+	}
+
+	switch n.typ {
+	case NodeText:
+		return yield(path)
+	case NodeRange:
+		i, _ := n.r.index(m.s[pos:end])
+		return yield(appendChoice(path, Choice{n.node, i}))
+	case NodePhrase:
+		return m.partPaths(n, 0, pos, end, path, yield)
+	}
+
+	inner, innerEnd := pos+len(n.open), end-len(n.close)
+	for i, phrase := range n.phrases {
+		p := path
+		switch {
+		case n.choice:
+			p = appendChoice(path, Choice{n.node, int64(i)})
+		case len(n.phrases) > 1:
+			// the root list still needs its choice for Decompose, so it
+			// is recorded without a node, see visible:
+			p = appendChoice(path, Choice{nil, int64(i)})
+		}
+		if innerEnd >= inner && !m.paths(phrase, inner, innerEnd, p, yield) {
+			return false
+		}
+	}
+	if n.optional && pos == end {
+		return yield(appendChoice(path, Choice{n.node, int64(len(n.phrases))}))
+	}
+	return true
+}
+
+// partPaths is like paths for the parts of the phrase n from index i
+// on.
+func (m *matcher) partPaths(n *matchNode, i, pos, end int, path []Choice, yield func([]Choice) bool) bool {
+	if i == len(n.parts) {
+		return pos != end || yield(path)
+	}
+
+	for _, e := range m.endsOf(n.parts[i], -1, pos) {
+		if e > end || !contains(m.endsOf(n, i+1, e), end) {
+			continue
+		}
+		more := m.paths(n.parts[i], pos, e, path, func(p []Choice) bool {
+			return m.partPaths(n, i+1, e, end, p, yield)
+		})
+		if !more {
+			return false
+		}
+	}
+	return true
+}
+
+// visible returns the choices in path without those of a root list
+// that are not returned, since TreatRootAsList is not set.
+func visible(path []Choice) []Choice {
+	if len(path) > 0 && path[0].Node == nil {
+		return path[1:]
+	}
+	return path
+}
+
+// appendChoice appends c to a copy of path, which other paths may
+// share.
+func appendChoice(path []Choice, c Choice) []Choice {
+	return append(path[:len(path):len(path)], c)
+}
+
+func contains(sorted []int, n int) bool {
+	i := sort.SearchInts(sorted, n)
+	return i < len(sorted) && sorted[i] == n
+}
+
+func unique(ints []int) []int {
+	sort.Ints(ints)
+	result := ints[:0]
+	for i, n := range ints {
+		if i == 0 || n != ints[i-1] {
+			result = append(result, n)
+		}
+	}
+	return result
+}
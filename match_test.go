@@ -0,0 +1,150 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	testMatch(t, expandTests, parse)
+	testMatch(t, quoteTests, parseQuotes)
+	testMatch(t, rangeTests, parse)
+	testMatch(t, charRangeTests, parse)
+	testMatch(t, radixRangeTests, parse)
+	testMatch(t, lenientTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.Lenient = true
+		return New().ParseCustom(input, opts)
+	})
+	testMatch(t, expandTestsCustom, parseCustom)
+}
+
+// testMatch checks that the strings of the expansion match, and that
+// strings close to them only match if they are part of the expansion.
+func testMatch(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			expansion := map[string]bool{}
+			for _, s := range test.output {
+				expansion[s] = true
+			}
+
+			candidates := []string{"", "x", "{}", "()"}
+			for _, s := range test.output {
+				candidates = append(candidates, s, s+"x", "x"+s, s+s)
+				if s != "" {
+					candidates = append(candidates, s[1:], s[:len(s)-1])
+				}
+			}
+
+			for _, s := range candidates {
+				if have := tree.Match(s); have != expansion[s] {
+					t.Errorf("%q: want %t, have %t", s, expansion[s], have)
+				}
+				if _, have := tree.MatchChoices(s); have != expansion[s] {
+					t.Errorf("%q: want choices %t, have %t", s, expansion[s], have)
+				}
+			}
+		})
+	}
+}
+
+// choiceString prints the choices with the positions of their nodes.
+func choiceString(choices []Choice) string {
+	s := ""
+	for _, c := range choices {
+		s += fmt.Sprintf("%d:%d ", c.Node.Position().Pos, c.Index)
+	}
+	return s
+}
+
+func TestMatchChoices(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	choiceTests := []struct {
+		input   string
+		opts    ParseOpts
+		s       string
+		choices string
+	}{
+		{"foo-{a,b}{1,2,3}", BashOpts(), "foo-b2", "4:1 9:1 "},
+		{"foo-{a,b}{1,2,3}", BashOpts(), "foo-a3", "4:0 9:2 "},
+		{"web{001..120}", BashOpts(), "web042", "3:41 "},
+		{"{a,{b,c}}x{}", BashOpts(), "cx{}", "0:1 3:1 "},
+		{"{a}{1..10..3}", BashOpts(), "{a}7", "0:0 3:2 "},
+		{"{z..a..5}", BashOpts(), "p", "0:2 "},
+		{"{0x00..0xff}", BashOpts(), "0x1a", "0:26 "},
+		{"x(a)y", customOpts, "xy", "0:0 1:1 "},
+		{"x(a)y", customOpts, "xay", "0:0 1:0 "},
+		{"a,(b,c)", customOpts, "c", "0:1 2:1 "},
+		{"abc", BashOpts(), "abc", ""},
+	}
+
+	for _, ct := range choiceTests {
+		t.Run(ct.input+" "+ct.s, func(t *testing.T) {
+			tree, err := New().ParseCustom(ct.input, ct.opts)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			choices, ok := tree.MatchChoices(ct.s)
+			if !ok {
+				t.Fatal("Expected a match")
+			}
+			if have := choiceString(choices); have != ct.choices {
+				t.Errorf("want %q, have %q", ct.choices, have)
+			}
+		})
+	}
+}
+
+func TestMatchRange(t *testing.T) {
+	matchRangeTests := []struct {
+		input string
+		yes   []string
+		no    []string
+	}{
+		{"{1..10..3}", []string{"1", "4", "7", "10"}, []string{"2", "0", "13", "01", "+4", "-1"}},
+		{"{-3..3}", []string{"-3", "0", "3"}, []string{"-4", "4", "-0", "+1"}},
+		{"{01..10}", []string{"01", "09", "10"}, []string{"1", "9", "001", "11"}},
+		{"{-05..05}", []string{"-05", "-01", "000", "005"}, []string{"-5", "5", "00", "05"}},
+		{"{0x0A..0x0F}", []string{"0x0A", "0x0F"}, []string{"0x0a", "0xA", "0X0A", "0x10"}},
+		{"{a..e..2}", []string{"a", "c", "e"}, []string{"b", "d", "A", "ab"}},
+		{"{α..γ}", []string{"α", "β", "γ"}, []string{"δ", "a"}},
+		{"{X..c}", []string{"", "[", "]", "a"}, []string{`\`, "W", "d"}},
+	}
+
+	for _, mt := range matchRangeTests {
+		tree, err := parse(mt.input)
+		if err != nil {
+			t.Fatalf("%q: Parse error: %v", mt.input, err)
+		}
+		for _, s := range mt.yes {
+			if !tree.Match(s) {
+				t.Errorf("%q: Expected %q to match", mt.input, s)
+			}
+		}
+		for _, s := range mt.no {
+			if tree.Match(s) {
+				t.Errorf("%q: Expected %q not to match", mt.input, s)
+			}
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	tree, err := parse("{a..z}{a..z}{a..z}{a..z}{0..9}{0..9}-{x,y{1..100}}")
+	if err != nil {
+		b.Fatalf("Parse error: %v", err)
+	}
+	for i := 0; i < b.N; i++ {
+		if !tree.Match("hell42-y77") {
+			b.Fatal("Expected a match")
+		}
+	}
+}
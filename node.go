@@ -1,36 +1,81 @@
 package braceexpansion
 
-type Node interface{}
+// Node is a TextNode, ListNode, PhraseNode or RangeNode. Use a type
+// switch or Type to tell them apart.
+type Node interface {
+	Type() NodeType
+	Position() Span
+}
 
+// NodeType identifies the type of a node.
 type NodeType int
 
 const (
 	NodeList NodeType = iota
 	NodePhrase
 	NodeText
+	NodeRange
 )
 
+// ListNode holds the alternatives between a pair of braces, one
+// PhraseNode each. It spans from its opening to its closing brace. The
+// root list spans the whole input and has no braces, so Open and Close
+// are NoPos, as is Close for a list that ParseAll closed at EOF.
 type ListNode struct {
-	NodeType
-	Phrases []PhraseNode
-	Tree    *Tree
+	Span
+	Phrases    []PhraseNode
+	Open       Pos   // offset of the opening brace
+	Close      Pos   // offset of the closing brace
+	Separators []Pos // offsets of the separators between the phrases
+	Tree       *Tree // whose ParseOpts apply, see Tree.NewList
+}
+
+func (ListNode) Type() NodeType {
+	return NodeList
+}
+
+// NewList returns a list of the phrases that belongs to t, for
+// rewriters that add lists to a parsed tree. Every ListNode needs a
+// Tree, since its ParseOpts decide how the list expands.
+func (t *Tree) NewList(phrases ...PhraseNode) ListNode {
+	l := t.newListNode()
+	l.Phrases = phrases
+	return l
 }
 
 func (l *ListNode) append(n PhraseNode) {
 	l.Phrases = append(l.Phrases, n)
 }
 
+func (l ListNode) opts() ParseOpts {
+	if l.Tree == nil {
+		panic("braceexpansion: ListNode without a Tree, use Tree.NewList")
+	}
+	return l.Tree.opts
+}
+
+// PhraseNode is a sequence of parts, whose expansions are combined like
+// in Cartesian.
 type PhraseNode struct {
-	NodeType
-	Parts []Node // TextNode or ListNode
+	Span
+	Parts []Node // TextNode, ListNode or RangeNode
 }
 
-func (p *PhraseNode) append(n Node) { // TextNode or ListNode
+func (PhraseNode) Type() NodeType {
+	return NodePhrase
+}
+
+// append adds n to the phrase, which then spans up to the end of n.
+func (p *PhraseNode) append(n Node) { // TextNode, ListNode or RangeNode
+	if len(p.Parts) == 0 {
+		p.Pos = n.Position().Pos
+	}
+	p.EndPos = n.Position().EndPos
 	p.Parts = append(p.Parts, n)
 }
 
 func (t *Tree) newListNode() ListNode {
-	return ListNode{Tree: t}
+	return ListNode{Open: NoPos, Close: NoPos, Tree: t}
 }
 
 func (t *Tree) newPhraseNode() PhraseNode {
@@ -38,18 +83,47 @@ func (t *Tree) newPhraseNode() PhraseNode {
 }
 
 func (t *Tree) newTextNode(val string) TextNode {
-	return TextNode{text: val}
+	return TextNode{Text: val}
 }
 
-func (t *Tree) newEmptyPhraseNode() PhraseNode {
-	return PhraseNode{Parts: []Node{t.newTextNode("")}}
+// newEmptyPhraseNode returns a phrase with empty text, which is placed
+// at pos without taking up space.
+func (t *Tree) newEmptyPhraseNode(pos Pos) PhraseNode {
+	text := t.newTextNode("")
+	text.Span = Span{pos, pos}
+	return PhraseNode{Span: text.Span, Parts: []Node{text}}
 }
 
 func (t *Tree) newPhraseNodeWithText(val string) PhraseNode {
 	return PhraseNode{Parts: []Node{t.newTextNode(val)}}
 }
 
+// TextNode is regular text. Escapes and quotes are already removed from
+// Text, unless ParseOpts say to keep them.
 type TextNode struct {
-	NodeType
-	text string
+	Span
+	Text string
+}
+
+func (TextNode) Type() NodeType {
+	return NodeText
+}
+
+// RangeNode is a sequence expression like "{1..10}", "{1..10..2}",
+// "{0x00..0xff}" or "{a..z}". Its elements are computed when expanding
+// instead of being stored in the tree.
+type RangeNode struct {
+	Span
+	Start  int64
+	End    int64
+	Incr   int64  // 0 if omitted
+	Width  int    // zero-padded to this width, 0 if not padded
+	Chars  bool   // Start and End are code points, not numbers
+	Radix  int    // 16, 8 or 2, 0 for decimal numbers
+	Prefix string // radix prefix like "0x" printed before each number
+	Upper  bool   // print hexadecimal digits in upper case
+}
+
+func (RangeNode) Type() NodeType {
+	return NodeRange
 }
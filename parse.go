@@ -1,16 +1,28 @@
 package braceexpansion
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Tree struct {
 	Root      *ListNode
+	ctx       context.Context
 	lex       *lexer
+	items     []item // balanced items in lenient mode
 	token     item
 	peekCount int
+	depth     int // nesting depth of lists
 	opts      ParseOpts
+
+	recovering bool          // collect syntax errors instead of failing
+	errors     []*ParseError // collected while recovering
 }
 
 type ParseOpts struct {
@@ -19,6 +31,44 @@ type ParseOpts struct {
 	Separator             string
 	TreatRootAsList       bool
 	TreatSingleAsOptional bool
+	Ranges                bool // sequence expressions like {1..10}
+	RangeWidth            int  // zero-pad numbers in sequences to this width
+	RangeASCII            bool // reject character sequences like {α..ω}
+	RangeRadix            int  // read numbers in sequences in base 16, 8 or 2
+
+	// Escape makes the rune following it regular text, so with a
+	// backslash like in bash, {a\,b,c} expands to "a,b" and "c". The
+	// escapes are removed from the output unless KeepEscapes is set.
+	Escape      string
+	KeepEscapes bool
+
+	// Quotes makes single and double quoted regions regular text. The
+	// quotes are removed from the output unless KeepQuotes is set.
+	// BashOpts leaves it off, since a shell has already removed the
+	// quotes from its arguments.
+	Quotes     bool
+	KeepQuotes bool
+
+	// Lenient treats unmatched braces as regular text like bash does,
+	// instead of failing, e.g. "{a,b" expands to "{a,b".
+	Lenient bool
+
+	// Limits make parsing fail with a LimitError if the pattern would
+	// expand to more than the caller can afford. 0 means no limit. They
+	// do not bound the time parsing takes, which grows with the length
+	// of the pattern, and with Lenient the whole pattern is lexed before
+	// any limit is checked, so limit the length of untrusted patterns
+	// as well.
+	MaxResults int // number of strings in the expansion
+	MaxDepth   int // nesting depth of braces
+	MaxLength  int // length of each string in bytes
+	MaxBytes   int // total length of all strings in bytes
+}
+
+// BashOpts returns the options used by Parse, which follow the
+// traditional brace expansion of bash.
+func BashOpts() ParseOpts {
+	return ParseOpts{OpenBrace: "{", CloseBrace: "}", Separator: ",", Ranges: true, Escape: "\\"}
 }
 
 func (t *Tree) recover(err *error) {
@@ -28,7 +78,6 @@ func (t *Tree) recover(err *error) {
 			panic(e)
 		}
 		if t != nil {
-			t.lex.drain()
 			t.stopParse()
 		}
 		*err = e.(error)
@@ -39,23 +88,58 @@ func (t *Tree) recover(err *error) {
 func (t *Tree) startParse(l *lexer) {
 	t.Root = nil
 	t.lex = l
+	t.items = nil
+	t.depth = 0
+	t.errors = nil
+	if t.opts.Lenient {
+		t.items = balance(l.collect())
+	}
 }
 
 func (t *Tree) stopParse() {
+	t.ctx = nil
 	t.lex = nil
+	t.items = nil
+}
+
+// balance turns open and close items that have no counterpart into
+// text, so they are printed as they are.
+func balance(items []item) []item {
+	open := []int{}
+
+	for i, item := range items {
+		switch item.typ {
+		case itemOpen:
+			open = append(open, i)
+		case itemClose:
+			if len(open) == 0 {
+				items[i].typ = itemText
+			} else {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	for _, i := range open {
+		items[i].typ = itemText
+	}
+
+	return items
 }
 
 func (t *Tree) Parse(input string) (tree *Tree, err error) {
-	defer t.recover(&err)
-	opts := ParseOpts{OpenBrace: "{", CloseBrace: "}", Separator: ","}
-	t.opts = opts
-	t.startParse(lex(input, opts))
-	t.parseRoot()
-	return t, nil
+	return t.ParseCustom(input, BashOpts())
 }
 
 func (t *Tree) ParseCustom(input string, opts ParseOpts) (tree *Tree, err error) {
+	return t.ParseContext(context.Background(), input, opts)
+}
+
+// ParseContext is like ParseCustom, but gives up with ctx.Err() when
+// ctx is done.
+func (t *Tree) ParseContext(ctx context.Context, input string, opts ParseOpts) (tree *Tree, err error) {
 	defer t.recover(&err)
+	t.ctx = ctx
 	t.opts = opts
 	t.startParse(lex(input, opts))
 	if opts.TreatRootAsList {
@@ -63,6 +147,32 @@ func (t *Tree) ParseCustom(input string, opts ParseOpts) (tree *Tree, err error)
 	} else {
 		t.parseRoot()
 	}
+	t.checkLimits()
+	t.stopParse()
+	return t, nil
+}
+
+// ParseAll is like ParseCustom, but does not stop at the first syntax
+// error. It skips stray closing braces, closes lists that are still open
+// at EOF and reads unterminated quotes as regular text, so it returns a
+// best-effort tree along with an ErrorList of all problems in the
+// input. Limit errors still fail without a tree.
+func (t *Tree) ParseAll(input string, opts ParseOpts) (tree *Tree, err error) {
+	t.recovering = true
+	defer func() {
+		t.recovering = false
+	}()
+
+	if _, err := t.ParseCustom(input, opts); err != nil {
+		return nil, err
+	}
+	if len(t.errors) > 0 {
+		errs := ErrorList(t.errors)
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].Offset < errs[j].Offset
+		})
+		return t, errs
+	}
 	return t, nil
 }
 
@@ -70,80 +180,102 @@ func (t *Tree) ParseCustom(input string, opts ParseOpts) (tree *Tree, err error)
 // compatibility with traditional brace expansion.
 func (t *Tree) parseRoot() {
 	ln := t.newListNode()
+	ln.Span = Span{0, Pos(len(t.lex.input))}
 	t.Root = &ln
 
-	pn := &PhraseNode{}
+	pn := t.newPhraseNode()
 
 	for t.peek().typ != itemEOF {
-		if t.peek().typ == itemText || t.peek().typ == itemOpen {
+		if t.peek().typ == itemText || t.peek().typ == itemOpen || t.peek().typ == itemRange {
 			pn.append(t.exprOrText())
 		} else if t.peek().typ == itemSeparator {
-			t.next()
-			pn.append(t.newTextNode(t.opts.Separator))
+			text := t.newTextNode(t.opts.Separator)
+			text.Span = t.next().span()
+			pn.append(text)
 		} else {
-			t.errorf("unexpected item type %v in root", t.peek().typ)
+			// skip stray closing braces when recovering:
+			t.unexpected("root")
+			t.next()
 		}
 	}
 
 	if t.Root != nil {
-		t.Root.append(*pn)
+		t.Root.append(pn)
 	}
 }
 
 // parseRootList is essentially the same as list, except it runs until EOF.
 func (t *Tree) parseRootList() {
 	ln := t.newListNode()
+	ln.Span = Span{0, Pos(len(t.lex.input))}
 	t.Root = &ln
 
 	if t.peek().typ == itemSeparator || t.peek().typ == itemEOF {
-		t.Root.append(t.newEmptyPhraseNode())
+		t.Root.append(t.newEmptyPhraseNode(Pos(t.peek().pos)))
 	}
 
 	for t.peek().typ != itemEOF {
-		if t.peek().typ == itemText || t.peek().typ == itemOpen {
+		if t.peek().typ == itemText || t.peek().typ == itemOpen || t.peek().typ == itemRange {
 			t.Root.append(t.phrase())
 
 		} else if t.peek().typ == itemSeparator {
-			t.next()
+			t.Root.Separators = append(t.Root.Separators, Pos(t.next().pos))
 
 			if t.peek().typ == itemSeparator || t.peek().typ == itemEOF {
-				t.Root.append(t.newEmptyPhraseNode())
+				t.Root.append(t.newEmptyPhraseNode(Pos(t.peek().pos)))
 			}
 		} else {
-			t.errorf("unexpected item type %v in root", t.peek().typ)
+			// skip stray closing braces when recovering:
+			t.unexpected("root")
+			t.next()
 		}
 	}
 }
 
-func (t *Tree) list() ListNode {
+// list parses the list that starts with the opening brace open.
+func (t *Tree) list(open item) ListNode {
+	t.depth++
+	if t.opts.MaxDepth > 0 && t.depth > t.opts.MaxDepth {
+		t.error(&LimitError{Limit: LimitDepth, Max: t.opts.MaxDepth})
+	}
+
 	ln := t.newListNode()
+	ln.Open = Pos(open.pos)
+	ln.Pos = ln.Open
 
 	if t.peek().typ == itemSeparator {
-		ln.append(t.newEmptyPhraseNode())
+		ln.append(t.newEmptyPhraseNode(Pos(t.peek().pos)))
 	}
 
 	for t.peek().typ != itemClose {
-		if t.peek().typ == itemText || t.peek().typ == itemOpen {
+		if t.peek().typ == itemText || t.peek().typ == itemOpen || t.peek().typ == itemRange {
 			ln.append(t.phrase())
 		} else if t.peek().typ == itemSeparator {
-			t.next()
+			ln.Separators = append(ln.Separators, Pos(t.next().pos))
 			if t.peek().typ == itemSeparator || t.peek().typ == itemClose {
-				ln.append(t.newEmptyPhraseNode())
+				ln.append(t.newEmptyPhraseNode(Pos(t.peek().pos)))
 			}
 		} else {
-			t.errorf("unexpected item type %v in list", t.peek().typ)
+			// only EOF gets here, which closes the list when recovering:
+			t.unexpected("list", itemClose)
+			ln.EndPos = Pos(t.peek().pos)
+			t.depth--
+			return ln
 		}
 	}
 
-	t.next()
+	end := t.next()
+	ln.Close = Pos(end.pos)
+	ln.EndPos = end.span().EndPos
+	t.depth--
 
 	return ln
 }
 
 func (t *Tree) phrase() PhraseNode {
-	pn := PhraseNode{}
+	pn := t.newPhraseNode()
 
-	for t.peek().typ == itemText || t.peek().typ == itemOpen {
+	for t.peek().typ == itemText || t.peek().typ == itemOpen || t.peek().typ == itemRange {
 		pn.append(t.exprOrText())
 	}
 
@@ -155,24 +287,97 @@ func (t *Tree) exprOrText() Node {
 	case itemText:
 		return t.text()
 	case itemOpen:
-		t.next()
-		return t.list()
+		return t.list(t.next())
+	case itemRange:
+		return t.rangeExpr()
 	default:
-		t.errorf("unexpected item type %v expression or text", t.peek().typ)
+		t.unexpected("expression or text", itemText, itemOpen, itemRange)
 	}
 
 	panic("not reached")
 }
 
 func (t *Tree) text() TextNode {
-	return t.newTextNode(t.next().val)
+	it := t.next()
+	text := t.newTextNode(literal(it.val, t.opts))
+	text.Span = it.span()
+	return text
+}
+
+// literal removes escapes and quotes from the text s, unless ParseOpts
+// say to keep them. An escape at the very end is kept, since it escapes
+// nothing. Like in bash, escapes are regular text in single quotes, and
+// in double quotes they only escape '"', '$', '`', newlines and
+// themselves.
+func literal(s string, opts ParseOpts) string {
+	escape := opts.Escape
+	if (escape == "" || !strings.Contains(s, escape)) && (!opts.Quotes || !strings.ContainsAny(s, "'\"")) {
+		return s
+	}
+
+	var buf bytes.Buffer
+	var quote rune // the quote we are in, 0 if none
+
+	for len(s) > 0 {
+		if escape != "" && quote != '\'' && strings.HasPrefix(s, escape) && len(s) > len(escape) {
+			rest := s[len(escape):]
+			_, w := utf8.DecodeRuneInString(rest)
+			if quote == '"' && !strings.HasPrefix(rest, escape) && !strings.ContainsAny(rest[:w], "\"$`\n") {
+				buf.WriteString(escape)
+				s = rest
+				continue
+			}
+			if opts.KeepEscapes {
+				buf.WriteString(s[:len(escape)+w])
+			} else {
+				buf.WriteString(rest[:w])
+			}
+			s = rest[w:]
+			continue
+		}
+
+		r, w := utf8.DecodeRuneInString(s)
+		if opts.Quotes && (r == '\'' || r == '"') && (quote == 0 || quote == r) {
+			if quote == 0 {
+				quote = r
+			} else {
+				quote = 0
+			}
+			if opts.KeepQuotes {
+				buf.WriteRune(r)
+			}
+		} else {
+			buf.WriteString(s[:w])
+		}
+		s = s[w:]
+	}
+
+	return buf.String()
+}
+
+func (t *Tree) rangeExpr() RangeNode {
+	it := t.next()
+	val := it.val
+	body := val[len(t.opts.OpenBrace) : len(val)-len(t.opts.CloseBrace)]
+	r, ok := parseRange(body, t.opts.RangeRadix)
+	if !ok {
+		t.errorf(it, "invalid sequence expression %q", val)
+	}
+	if r.Chars && t.opts.RangeASCII && (r.Start > unicode.MaxASCII || r.End > unicode.MaxASCII) {
+		t.errorf(it, "sequence expression %q is not limited to ASCII letters", val)
+	}
+	if t.opts.RangeWidth > 0 && !r.Chars {
+		r.Width = t.opts.RangeWidth
+	}
+	r.Span = it.span()
+	return r
 }
 
 func (t *Tree) next() item {
 	if t.peekCount > 0 {
 		t.peekCount--
 	} else {
-		t.token = t.lex.nextItem()
+		t.token = t.nextItem()
 	}
 	return t.token
 }
@@ -182,19 +387,73 @@ func (t *Tree) peek() item {
 		return t.token
 	}
 	t.peekCount = 1
-	t.token = t.lex.nextItem()
+	t.token = t.nextItem()
 	return t.token
 }
 
+// nextItem returns the next item from the lexer, or from the balanced
+// items in lenient mode, and turns lexer errors into parse errors.
+func (t *Tree) nextItem() item {
+	select {
+	case <-t.ctx.Done():
+		t.error(t.ctx.Err())
+	default:
+	}
+
+	for {
+		var item item
+		if t.items != nil {
+			// the last item is EOF and stays:
+			item = t.items[0]
+			if len(t.items) > 1 {
+				t.items = t.items[1:]
+			}
+		} else {
+			item = t.lex.nextItem()
+		}
+		if item.typ != itemError {
+			return item
+		}
+		t.errorf(item, "%s", item.val)
+	}
+}
+
 func New() *Tree {
 	return &Tree{}
 }
 
-func (t *Tree) errorf(format string, args ...interface{}) {
-	t.Root = nil
-	panic(fmt.Errorf(format, args...))
+// errorf reports a ParseError at the position of the item it.
+func (t *Tree) errorf(it item, format string, args ...interface{}) {
+	t.report(newParseError(t.lex.input, it.pos, nil, it.typ, fmt.Sprintf(format, args...)))
+}
+
+// unexpected reports a ParseError for the next item, which is not valid
+// in the given context.
+func (t *Tree) unexpected(context string, expected ...itemType) {
+	it := t.peek()
+	msg := fmt.Sprintf("unexpected %s %q in %s", it.typ, it.val, context)
+	if it.typ == itemEOF {
+		msg = fmt.Sprintf("unexpected EOF in %s", context)
+	}
+	t.report(newParseError(t.lex.input, it.pos, expected, it.typ, msg))
+}
+
+// report fails with err, unless the parser is recovering: then err is
+// collected, and the caller has to get back on track. Lists still open
+// at EOF all report the same error, which is collected only once.
+func (t *Tree) report(err *ParseError) {
+	if !t.recovering {
+		t.error(err)
+	}
+	for _, e := range t.errors {
+		if e.Offset == err.Offset && e.Msg == err.Msg {
+			return
+		}
+	}
+	t.errors = append(t.errors, err)
 }
 
 func (t *Tree) error(err error) {
-	t.errorf("%s", err)
+	t.Root = nil
+	panic(err)
 }
@@ -1,7 +1,10 @@
 package braceexpansion
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -14,7 +17,17 @@ func (p PhraseNode) String() string {
 }
 
 func (t TextNode) String() string {
-	return fmt.Sprintf("\"%s\"", t.text)
+	return fmt.Sprintf("\"%s\"", t.Text)
+}
+
+func (r RangeNode) String() string {
+	if r.Chars {
+		return fmt.Sprintf("Range: %c..%c", rune(r.Start), rune(r.End))
+	}
+	if r.Incr != 0 {
+		return fmt.Sprintf("Range: %d..%d..%d", r.Start, r.End, r.Incr)
+	}
+	return fmt.Sprintf("Range: %d..%d", r.Start, r.End)
 }
 
 type parseTest struct {
@@ -47,12 +60,24 @@ var parseTests = []parseTest{
 	{"{abc,def}", true, `List: [Phrase: [List: [Phrase: ["abc"] Phrase: ["def"]]]]`},
 	{"{a,b}{1,2}", true, `List: [Phrase: [List: [Phrase: ["a"] Phrase: ["b"]] List: [Phrase: ["1"] Phrase: ["2"]]]]`},
 	{"{abc}def", true, `List: [Phrase: [List: [Phrase: ["abc"]] "def"]]`},
+	{"{1..3}", true, `List: [Phrase: [Range: 1..3]]`},
+	{"a{-3..3}b", true, `List: [Phrase: ["a" Range: -3..3 "b"]]`},
+	{"{a,{1..3}}", true, `List: [Phrase: [List: [Phrase: ["a"] Phrase: [Range: 1..3]]]]`},
+	{"{1..3}{a,b}", true, `List: [Phrase: [Range: 1..3 List: [Phrase: ["a"] Phrase: ["b"]]]]`},
+	{"{1..a}", true, `List: [Phrase: [List: [Phrase: ["1..a"]]]]`},
+	{"{1..20..5}", true, `List: [Phrase: [Range: 1..20..5]]`},
+	{"{1..3..1..2}", true, `List: [Phrase: [List: [Phrase: ["1..3..1..2"]]]]`},
+	{"{a..f}", true, `List: [Phrase: [Range: a..f]]`},
+	{"{α..ω}", true, `List: [Phrase: [Range: α..ω]]`},
+	{"{a..5}", true, `List: [Phrase: [List: [Phrase: ["a..5"]]]]`},
 	{"}", false, ``},
 	{"}}", false, ``},
 	{"{{}", false, ``},
 	{"{}}", false, ``},
 	{"{,abc", false, ``},
 	{"{abc,def", false, ``},
+	{"{a,'b}", true, `List: [Phrase: [List: [Phrase: ["a"] Phrase: ["'b"]]]]`},
+	{`"abc`, true, `List: [Phrase: [""abc"]]`},
 }
 
 var parseTestsCustom = []parseTest{
@@ -124,7 +149,89 @@ func parse(input string) (*Tree, error) {
 	return New().Parse(input)
 }
 
+func TestParseLenientCustom(t *testing.T) {
+	opts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true, Lenient: true}
+	tree, err := New().ParseCustom("a),(b,c", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `List: [Phrase: ["a" ")"] Phrase: ["(" "b"] Phrase: ["c"]]`
+	if have := fmt.Sprintf("%v", tree.Root); have != want {
+		t.Errorf("Unexpected tree.\nWant:\n%v\nHave:\n%v", want, have)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tree := New()
+	_, err := tree.ParseContext(ctx, "{a,b}{1,2}", BashOpts())
+	if err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+	if tree.Root != nil {
+		t.Errorf("Unexpected tree: %v", tree.Root)
+	}
+}
+
+func TestParseRangeASCII(t *testing.T) {
+	opts := BashOpts()
+	opts.RangeASCII = true
+
+	if _, err := New().ParseCustom("{X..c}", opts); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := New().ParseCustom("x{α..ω}", opts); err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
+func parseQuotes(input string) (*Tree, error) {
+	opts := BashOpts()
+	opts.Quotes = true
+	return New().ParseCustom(input, opts)
+}
+
 func parseCustom(input string) (*Tree, error) {
 	opts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
 	return New().ParseCustom(input, opts)
 }
+
+func BenchmarkParseShort(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New().Parse("web-{a,b}{01..20}.example.com"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseLong(b *testing.B) {
+	input := strings.Repeat("{a,b{c,d},'e,f'}x{1..3}\\,", 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New().Parse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseUnclosed parses braces that are never closed, each of
+// which is checked for a sequence expression. The time per byte should
+// not grow with the length of the input.
+func BenchmarkParseUnclosed(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		input := strings.Repeat("{a", n) + "}"
+		opts := BashOpts()
+		opts.Lenient = true
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if _, err := New().ParseCustom(input, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package braceexpansion
+
+// Pos is a byte offset in the input of the parser.
+type Pos int
+
+// NoPos marks a position that does not exist, like the braces of the
+// root list.
+const NoPos Pos = -1
+
+// Span is the part of the input a node was parsed from: the bytes from
+// Pos up to, but not including, EndPos. Nodes that are not built by the
+// parser have an empty span.
+type Span struct {
+	Pos    Pos
+	EndPos Pos
+}
+
+// Position returns the span, which makes every node that embeds it a
+// Node.
+func (s Span) Position() Span {
+	return s
+}
+
+// Contains reports whether the byte at offset is part of the span.
+func (s Span) Contains(offset Pos) bool {
+	return s.Pos <= offset && offset < s.EndPos
+}
+
+// span returns the part of the input the item was read from.
+func (it item) span() Span {
+	return Span{Pos(it.pos), Pos(it.pos + len(it.val))}
+}
+
+// NodeAt returns the innermost node that contains the byte at offset,
+// or nil if the offset is outside of the input. On a brace or a
+// separator, this is the ListNode they belong to.
+func (t *Tree) NodeAt(offset int) Node {
+	if t.Root == nil || !t.Root.Contains(Pos(offset)) {
+		return nil
+	}
+	return nodeAt(*t.Root, Pos(offset))
+}
+
+func nodeAt(n Node, offset Pos) Node {
+	switch node := n.(type) {
+	case ListNode:
+		for _, phrase := range node.Phrases {
+			if phrase.Contains(offset) {
+				return nodeAt(phrase, offset)
+			}
+		}
+	case PhraseNode:
+		for _, part := range node.Parts {
+			if part.Position().Contains(offset) {
+				return nodeAt(part, offset)
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,108 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNodeAt(t *testing.T) {
+	tree, err := parse("a{b,c}{1..3}x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	nodeAtTests := []struct {
+		offset int
+		node   string // type and span
+	}{
+		{0, "TextNode 0-1"},
+		{1, "ListNode 1-6"},
+		{2, "TextNode 2-3"},
+		{3, "ListNode 1-6"},
+		{4, "TextNode 4-5"},
+		{5, "ListNode 1-6"},
+		{6, "RangeNode 6-12"},
+		{11, "RangeNode 6-12"},
+		{12, "TextNode 12-13"},
+		{13, "<nil>"},
+		{-1, "<nil>"},
+	}
+
+	for _, nt := range nodeAtTests {
+		n := tree.NodeAt(nt.offset)
+		have := "<nil>"
+		if n != nil {
+			have = fmt.Sprintf("%T %d-%d", n, n.Position().Pos, n.Position().EndPos)
+			have = have[len("braceexpansion."):]
+		}
+		if have != nt.node {
+			t.Errorf("%d: want %s, have %s", nt.offset, nt.node, have)
+		}
+	}
+}
+
+func TestListPositions(t *testing.T) {
+	listTests := []struct {
+		input      string
+		opts       ParseOpts
+		span       Span
+		open       Pos
+		close      Pos
+		separators []Pos
+		phrases    []Span
+	}{
+		{"x{a,,b}", BashOpts(), Span{1, 7}, 1, 6, []Pos{3, 4}, []Span{{2, 3}, {4, 4}, {5, 6}}},
+		{"x{,a}", BashOpts(), Span{1, 5}, 1, 4, []Pos{2}, []Span{{2, 2}, {3, 4}}},
+		{"x{}", BashOpts(), Span{1, 3}, 1, 2, nil, nil},
+		{"x{{a},b}", BashOpts(), Span{1, 8}, 1, 7, []Pos{5}, []Span{{2, 5}, {6, 7}}},
+		{"x<<a|b>>", ParseOpts{OpenBrace: "<<", CloseBrace: ">>", Separator: "|"}, Span{1, 8}, 1, 6, []Pos{4}, []Span{{3, 4}, {5, 6}}},
+	}
+
+	for _, lt := range listTests {
+		t.Run(lt.input, func(t *testing.T) {
+			tree, err := New().ParseCustom(lt.input, lt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ln := tree.Root.Phrases[0].Parts[1].(ListNode)
+
+			if ln.Span != lt.span || ln.Open != lt.open || ln.Close != lt.close || !reflect.DeepEqual(ln.Separators, lt.separators) {
+				t.Errorf("want %v %d %d %v, have %v %d %d %v", lt.span, lt.open, lt.close, lt.separators, ln.Span, ln.Open, ln.Close, ln.Separators)
+			}
+
+			var phrases []Span
+			for _, pn := range ln.Phrases {
+				phrases = append(phrases, pn.Span)
+			}
+			if !reflect.DeepEqual(phrases, lt.phrases) {
+				t.Errorf("want phrases %v, have %v", lt.phrases, phrases)
+			}
+		})
+	}
+}
+
+func TestRootPositions(t *testing.T) {
+	opts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true}
+	tree, err := New().ParseCustom("a,,(b)", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	root := tree.Root
+	if root.Span != (Span{0, 6}) || root.Open != NoPos || root.Close != NoPos || !reflect.DeepEqual(root.Separators, []Pos{1, 2}) {
+		t.Errorf("Unexpected root: %v %d %d %v", root.Span, root.Open, root.Close, root.Separators)
+	}
+	if have := root.Phrases[1].Span; have != (Span{2, 2}) {
+		t.Errorf("want empty phrase at 2, have %v", have)
+	}
+}
+
+func TestRecoveredPositions(t *testing.T) {
+	tree, _ := New().ParseAll("x{a,b", BashOpts())
+
+	ln := tree.Root.Phrases[0].Parts[1].(ListNode)
+	if ln.Span != (Span{1, 5}) || ln.Close != NoPos {
+		t.Errorf("Unexpected list: %v %d", ln.Span, ln.Close)
+	}
+}
@@ -0,0 +1,237 @@
+package braceexpansion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Printer turns a tree back into a pattern, using the delimiters of its
+// ParseOpts. Text that would be read as a brace, a separator, an escape
+// or a quote is escaped, or quoted if there is no escape, so parsing
+// the pattern with the same options expands to the same strings.
+//
+// With KeepEscapes, text already contains the escapes of the input, so
+// it is only quoted where needed, or printed as it is if there are no
+// quotes or they are kept as well.
+type Printer struct {
+	// Canonical splices lists that make up a whole alternative into the
+	// surrounding list, so "{a,{b,c}}" is printed as "{a,b,c}".
+	Canonical bool
+}
+
+// Format returns the pattern of the tree.
+func (t *Tree) Format() (string, error) {
+	var buf bytes.Buffer
+	if err := (Printer{}).Fprint(&buf, t); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Fprint writes the pattern of the tree to w.
+func (p Printer) Fprint(w io.Writer, t *Tree) error {
+	pr := &printer{Printer: p, opts: t.opts}
+	pr.root(*t.Root)
+	return pr.flush(w)
+}
+
+// FprintNode writes the pattern of a node of the tree to w. A ListNode
+// is printed with its braces, even if it is the root.
+func (p Printer) FprintNode(w io.Writer, t *Tree, n Node) error {
+	pr := &printer{Printer: p, opts: t.opts}
+	pr.node(n, true)
+	return pr.flush(w)
+}
+
+type printer struct {
+	Printer
+	opts ParseOpts
+	buf  bytes.Buffer
+	err  error // the first error, which stops printing
+}
+
+func (p *printer) flush(w io.Writer) error {
+	if p.err != nil {
+		return p.err
+	}
+	_, err := p.buf.WriteTo(w)
+	return err
+}
+
+func (p *printer) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+// root prints the phrases of the root list without braces. Unless
+// TreatRootAsList is set, the parser puts everything into one phrase,
+// so more phrases only fit into a pair of braces.
+func (p *printer) root(ln ListNode) {
+	switch {
+	case p.opts.TreatRootAsList:
+		p.phrases(ln.Phrases, true)
+	case len(ln.Phrases) > 1:
+		p.list(ln)
+	default:
+		start := p.buf.Len()
+		for _, phrase := range ln.Phrases {
+			p.phrase(phrase, false)
+		}
+
+		// the parser reads an empty pattern as no strings at all:
+		if p.buf.Len() == start && len(ln.Phrases) == 1 && len(ln.Phrases[0].Parts) > 0 {
+			if !p.opts.Quotes {
+				p.errorf("cannot print an empty string without quotes")
+			}
+			p.buf.WriteString("''")
+		}
+	}
+}
+
+func (p *printer) node(n Node, inList bool) {
+	switch node := n.(type) {
+	case ListNode:
+		p.list(node)
+	case PhraseNode:
+		p.phrase(node, inList)
+	case TextNode:
+		p.text(node.Text, inList)
+	case RangeNode:
+		p.rangeExpr(node)
+	default:
+		p.errorf("cannot print node type %T", n)
+	}
+}
+
+func (p *printer) list(ln ListNode) {
+	p.buf.WriteString(p.opts.OpenBrace)
+	start := p.buf.Len()
+	p.phrases(ln.Phrases, len(ln.Phrases) > 1)
+	p.buf.WriteString(p.opts.CloseBrace)
+
+	// a list like "{1..3}" would be read as a sequence expression:
+	list := p.buf.String()[start-len(p.opts.OpenBrace):]
+	if p.opts.Ranges && rangeLen(list, p.opts) > 0 {
+		body := list[len(p.opts.OpenBrace):]
+		p.buf.Truncate(start)
+		_, w := utf8.DecodeRuneInString(body)
+		p.literal(body[:w])
+		p.buf.WriteString(body[w:])
+	}
+}
+
+// phrases prints the phrases of a list, separated by the separator.
+// Only lists with more than one phrase can be flattened, since single
+// phrases are printed in braces or are optional.
+func (p *printer) phrases(phrases []PhraseNode, canFlatten bool) {
+	if p.Canonical && canFlatten {
+		phrases = flatten(phrases, p.opts)
+	}
+	for i, phrase := range phrases {
+		if i > 0 {
+			p.buf.WriteString(p.opts.Separator)
+		}
+		p.phrase(phrase, true)
+	}
+}
+
+func (p *printer) phrase(pn PhraseNode, inList bool) {
+	for _, part := range pn.Parts {
+		p.node(part, inList)
+	}
+}
+
+// text prints s so it is read as regular text. Separators only need to
+// be escaped in lists, since the parser reads them as text at the root.
+func (p *printer) text(s string, inList bool) {
+	if p.opts.KeepEscapes && (p.opts.KeepQuotes || !p.opts.Quotes) {
+		p.buf.WriteString(s)
+		return
+	}
+
+	for len(s) > 0 {
+		_, w := utf8.DecodeRuneInString(s)
+		n := p.special(s, inList)
+		switch {
+		case n == 0:
+			n = w
+			p.buf.WriteString(s[:n])
+		case p.escape() != "":
+			// escaping the first rune is enough, the rest may start
+			// another token:
+			n = w
+			p.literal(s[:n])
+		default:
+			p.literal(s[:n])
+		}
+		s = s[n:]
+	}
+}
+
+// special returns the length of the token at the start of s, which
+// would not be read as text, or 0 if there is none. At the end of s,
+// the start of a token counts as well, since the next part of the
+// phrase might complete it.
+func (p *printer) special(s string, inList bool) int {
+	tokens := []string{p.opts.OpenBrace, p.opts.CloseBrace, p.opts.Escape}
+	if inList {
+		tokens = append(tokens, p.opts.Separator)
+	}
+	if p.opts.Quotes {
+		tokens = append(tokens, "'", `"`)
+	}
+
+	for _, token := range tokens {
+		switch {
+		case token == "":
+			continue
+		case strings.HasPrefix(s, token):
+			return len(token)
+		case strings.HasPrefix(token, s):
+			return len(s)
+		}
+	}
+	return 0
+}
+
+// escape returns the escape to print, which is none with KeepEscapes,
+// since the parser would keep it in the text.
+func (p *printer) escape() string {
+	if p.opts.KeepEscapes {
+		return ""
+	}
+	return p.opts.Escape
+}
+
+// literal prints s so it is read as regular text, using the escape or
+// else quotes.
+func (p *printer) literal(s string) {
+	switch {
+	case p.escape() != "":
+		p.buf.WriteString(p.escape())
+		p.buf.WriteString(s)
+	case p.opts.Quotes && !strings.Contains(s, "'"):
+		p.buf.WriteString("'" + s + "'")
+	case p.opts.Quotes && !strings.Contains(s, `"`):
+		p.buf.WriteString(`"` + s + `"`)
+	default:
+		p.errorf("cannot print %q as text without an escape or quotes", s)
+	}
+}
+
+func (p *printer) rangeExpr(r RangeNode) {
+	p.buf.WriteString(p.opts.OpenBrace)
+	p.buf.WriteString(r.format(r.Start))
+	p.buf.WriteString(rangeSeparator)
+	p.buf.WriteString(r.format(r.End))
+	if r.Incr != 0 {
+		p.buf.WriteString(rangeSeparator)
+		p.buf.WriteString(strconv.FormatInt(r.Incr, 10))
+	}
+	p.buf.WriteString(p.opts.CloseBrace)
+}
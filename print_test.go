@@ -0,0 +1,208 @@
+package braceexpansion
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/thomasheller/slicecmp"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	withOpts := func(f func(*ParseOpts)) ParseOpts {
+		opts := BashOpts()
+		f(&opts)
+		return opts
+	}
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	roundTripTests := []struct {
+		name  string
+		tests []expandTest
+		opts  ParseOpts
+	}{
+		{"expand", expandTests, BashOpts()},
+		{"quotes", quoteTests, withOpts(func(o *ParseOpts) { o.Quotes = true })},
+		{"range", rangeTests, BashOpts()},
+		{"charRange", charRangeTests, BashOpts()},
+		{"radixRange", radixRangeTests, BashOpts()},
+		{"hexRange", hexRangeTests, withOpts(func(o *ParseOpts) { o.RangeRadix = 16 })},
+		{"rangeWidth", rangeWidthTests, withOpts(func(o *ParseOpts) { o.RangeWidth = 3 })},
+		{"keepEscapes", keepEscapesTests, withOpts(func(o *ParseOpts) { o.KeepEscapes = true })},
+		{"keepQuotes", keepQuotesTests, withOpts(func(o *ParseOpts) { o.Quotes, o.KeepQuotes = true, true })},
+		{"lenient", lenientTests, withOpts(func(o *ParseOpts) { o.Lenient = true })},
+		{"custom", expandTestsCustom, customOpts},
+	}
+
+	for _, rt := range roundTripTests {
+		for _, test := range rt.tests {
+			for _, canonical := range []bool{false, true} {
+				tree, err := New().ParseCustom(test.input, rt.opts)
+				if err != nil {
+					t.Fatalf("%s: %q: Parse error: %v", rt.name, test.input, err)
+				}
+				testRoundTrip(t, tree, Printer{Canonical: canonical})
+			}
+		}
+	}
+}
+
+// testRoundTrip checks that the printed tree expands to the same
+// strings, and that printing it again gives the same pattern.
+func testRoundTrip(t *testing.T, tree *Tree, p Printer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := p.Fprint(&buf, tree); err != nil {
+		t.Errorf("Print error: %v", err)
+		return
+	}
+	pattern := buf.String()
+
+	reparsed, err := New().ParseCustom(pattern, tree.opts)
+	if err != nil {
+		t.Errorf("%q: Parse error: %v", pattern, err)
+		return
+	}
+
+	want, have := tree.Expand(), reparsed.Expand()
+	if !slicecmp.Equal(want, have) {
+		t.Errorf("%q: Unexpected output:\n%s", pattern, slicecmp.Sprint([]string{"want", "have"}, want, have))
+	}
+
+	buf.Reset()
+	if err := p.Fprint(&buf, reparsed); err != nil || buf.String() != pattern {
+		t.Errorf("want %q, have %q (%v)", pattern, buf.String(), err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+	quoteOpts := BashOpts()
+	quoteOpts.Quotes = true
+	keepEscapesOpts := quoteOpts
+	keepEscapesOpts.KeepEscapes = true
+
+	formatTests := []struct {
+		input     string
+		opts      ParseOpts
+		format    string
+		canonical string
+	}{
+		{"a{b,c}d", BashOpts(), "a{b,c}d", "a{b,c}d"},
+		{"{a,{b,c}}", BashOpts(), "{a,{b,c}}", "{a,b,c}"},
+		{"{{a,b},{c,{d,e}}}", BashOpts(), "{{a,b},{c,{d,e}}}", "{a,b,c,d,e}"},
+		{"{{a,b}}", BashOpts(), "{{a,b}}", "{{a,b}}"},
+		{"{a,{b}}", BashOpts(), "{a,{b}}", "{a,{b}}"},
+		{"{a,x{b,c}}", BashOpts(), "{a,x{b,c}}", "{a,x{b,c}}"},
+		{"{a\\,b,c}", BashOpts(), "{a\\,b,c}", "{a\\,b,c}"},
+		{"'{a,b}',c", quoteOpts, "\\{a,b\\},c", "\\{a,b\\},c"},
+		{`{"x,y",\\}`, quoteOpts, `{x\,y,\\}`, `{x\,y,\\}`},
+		{`{a,\'}`, quoteOpts, `{a,\'}`, `{a,\'}`},
+		{"{a,'}", BashOpts(), "{a,'}", "{a,'}"},
+		{"{1..3}{a..c..2}", BashOpts(), "{1..3}{a..c..2}", "{1..3}{a..c..2}"},
+		{"{01..10}{0x0A..0x0f}", BashOpts(), "{01..10}{0x0a..0x0f}", "{01..10}{0x0a..0x0f}"},
+		{"{0X0A..0X0F}", BashOpts(), "{0X0A..0X0F}", "{0X0A..0X0F}"},
+		{"{\\1..3}", BashOpts(), "{\\1..3}", "{\\1..3}"},
+		{"(a,(b))", customOpts, "(a,(b))", "a,b,"},
+		{"x(a,(b))", customOpts, "x(a,(b))", "x(a,b,)"},
+		{"(a,b),c", customOpts, "(a,b),c", "a,b,c"},
+		{"", customOpts, "", ""},
+		{"''", quoteOpts, "''", "''"},
+		{"", BashOpts(), "", ""},
+		{`{'{a}',\{}`, keepEscapesOpts, `{'{'a'}','\''{'}`, `{'{'a'}','\''{'}`},
+	}
+
+	for _, ft := range formatTests {
+		t.Run(ft.input, func(t *testing.T) {
+			tree, err := New().ParseCustom(ft.input, ft.opts)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			if have, err := tree.Format(); err != nil || have != ft.format {
+				t.Errorf("want %q, have %q (%v)", ft.format, have, err)
+			}
+
+			var buf bytes.Buffer
+			if err := (Printer{Canonical: true}).Fprint(&buf, tree); err != nil || buf.String() != ft.canonical {
+				t.Errorf("want canonical %q, have %q (%v)", ft.canonical, buf.String(), err)
+			}
+		})
+	}
+}
+
+// textTree returns a tree with a list of texts, followed by a list.
+func textTree(opts ParseOpts, texts ...string) *Tree {
+	tree := &Tree{opts: opts}
+	root := tree.newListNode()
+	tree.Root = &root
+
+	ln := tree.newListNode()
+	for _, text := range texts {
+		ln.append(tree.newPhraseNodeWithText(text))
+	}
+	inner := tree.newListNode()
+	inner.append(tree.newPhraseNodeWithText("x"))
+	inner.append(tree.newPhraseNodeWithText("y"))
+
+	pn := tree.newPhraseNode()
+	pn.append(ln)
+	pn.append(tree.newTextNode(opts.OpenBrace[:1]))
+	pn.append(inner)
+	root.append(pn)
+
+	return tree
+}
+
+func TestFormatText(t *testing.T) {
+	texts := []string{"a,b", "{", "}", "''", `"`, `\`, "<", "<<<", ">", "|", "{1..2}", "'\""}
+	quoteOpts := BashOpts()
+	quoteOpts.Quotes = true
+
+	formatTextTests := []struct {
+		name   string
+		opts   ParseOpts
+		format string
+	}{
+		{"bash", BashOpts(), `{a\,b,\{,\},'',",\\,<,<<<,>,|,\{1..2\},'"}\{{x,y}`},
+		{"bashQuotes", quoteOpts, `{a\,b,\{,\},\'\',\",\\,<,<<<,>,|,\{1..2\},\'\"}\{{x,y}`},
+		{"quotes", ParseOpts{OpenBrace: "{", CloseBrace: "}", Separator: ",", Quotes: true}, `{a','b,'{','}',"'""'",'"',\,<,<<<,>,|,'{'1..2'}',"'"'"'}'{'{x,y}`},
+		{"multi", ParseOpts{OpenBrace: "<<", CloseBrace: ">>", Separator: "|", Escape: `\`}, `<<a,b|{|}|''|"|\\|\<|\<\<\<|\>|\||{1..2}|'">>\<<<x|y>>`},
+	}
+
+	for _, ft := range formatTextTests {
+		t.Run(ft.name, func(t *testing.T) {
+			tree := textTree(ft.opts, texts...)
+			have, err := tree.Format()
+			if err != nil || have != ft.format {
+				t.Errorf("want %q, have %q (%v)", ft.format, have, err)
+			}
+			testRoundTrip(t, tree, Printer{})
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	opts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ","}
+	if s, err := textTree(opts, "a,b").Format(); err == nil {
+		t.Errorf("Expected error, got %q", s)
+	}
+}
+
+func TestFprintNode(t *testing.T) {
+	tree, err := parse("a{b,c{1..3}}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := (Printer{}).FprintNode(&buf, tree, *tree.Root); err != nil || buf.String() != "{a{b,c{1..3}}}" {
+		t.Errorf("want %q, have %q (%v)", "{a{b,c{1..3}}}", buf.String(), err)
+	}
+
+	buf.Reset()
+	n := tree.NodeAt(2)
+	if err := (Printer{}).FprintNode(&buf, tree, n); err != nil || buf.String() != "b" {
+		t.Errorf("want %q, have %q (%v)", "b", buf.String(), err)
+	}
+}
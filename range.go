@@ -0,0 +1,352 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rangeSeparator separates the bounds of a sequence expression.
+const rangeSeparator = ".."
+
+// rangeLen returns the length of the sequence expression at the start
+// of s, e.g. 7 for "{1..10}", or 0 if s does not start with one. It only
+// looks as far as the runes of a sequence expression go, so that lexing
+// stays linear for input like "{a{a{a...".
+func rangeLen(s string, opts ParseOpts) int {
+	if !strings.HasPrefix(s, opts.OpenBrace) {
+		return 0
+	}
+	body := s[len(opts.OpenBrace):]
+	n := strings.IndexFunc(body, func(r rune) bool { return !isRangeRune(r) })
+	if n >= 0 && n+len(opts.CloseBrace) < len(body) {
+		body = body[:n+len(opts.CloseBrace)]
+	}
+	end := strings.Index(body, opts.CloseBrace)
+	if end < 0 {
+		return 0
+	}
+	if _, ok := parseRange(body[:end], opts.RangeRadix); !ok {
+		return 0
+	}
+	return len(opts.OpenBrace) + end + len(opts.CloseBrace)
+}
+
+// isRangeRune reports whether r can be part of the bounds or the
+// increment of a sequence expression.
+func isRangeRune(r rune) bool {
+	return r == '.' || r == '-' || r == '+' || '0' <= r && r <= '9' || unicode.IsLetter(r)
+}
+
+// parseRange parses the text between the braces of a sequence
+// expression, with an optional increment like in "{1..10..2}". Like
+// bash, it rejects bounds that are neither integers nor single letters
+// or that are too large, so these are left to be printed as regular
+// text. Numbers without a prefix are read in the given radix, unless
+// it is 0 or 10.
+func parseRange(body string, radix int) (RangeNode, bool) {
+	bounds := strings.Split(body, rangeSeparator)
+	if len(bounds) != 2 && len(bounds) != 3 {
+		return RangeNode{}, false
+	}
+
+	r, ok := parseRadixBounds(bounds[0], bounds[1], radix)
+	if !ok {
+		r, ok = parseNumericBounds(bounds[0], bounds[1])
+	}
+	if !ok {
+		r, ok = parseCharBounds(bounds[0], bounds[1])
+	}
+	if !ok {
+		return RangeNode{}, false
+	}
+
+	if len(bounds) == 3 {
+		var err error
+		r.Incr, err = strconv.ParseInt(bounds[2], 10, 64)
+		if err != nil || r.Incr == math.MinInt64 {
+			return RangeNode{}, false
+		}
+	}
+
+	// the distance between the bounds has to fit into an int64:
+	if r.span() >= math.MaxInt64 {
+		return RangeNode{}, false
+	}
+
+	return r, true
+}
+
+func parseNumericBounds(first, last string) (RangeNode, bool) {
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return RangeNode{}, false
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return RangeNode{}, false
+	}
+
+	r := RangeNode{Start: start, End: end}
+
+	// like bash 4, pad to the width of the wider bound if either bound
+	// has a leading zero:
+	if zeroPadded(first) || zeroPadded(last) {
+		r.Width = len(first)
+		if len(last) > r.Width {
+			r.Width = len(last)
+		}
+	}
+
+	return r, true
+}
+
+// radixPrefixes maps the supported prefixes to their radix.
+var radixPrefixes = map[string]int{
+	"0x": 16, "0X": 16,
+	"0o": 8, "0O": 8,
+	"0b": 2, "0B": 2,
+}
+
+// radixBound is a bound of a sequence of hexadecimal, octal or binary
+// numbers.
+type radixBound struct {
+	n      int64
+	radix  int
+	prefix string
+	digits string
+}
+
+// parseRadixBound parses a number with a prefix like "0x", or without
+// a prefix in the given radix. Decimal numbers are left to
+// parseNumericBounds.
+func parseRadixBound(s string, radix int) (radixBound, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+
+	b := radixBound{radix: radix, digits: s}
+	if len(s) > 2 {
+		if r, ok := radixPrefixes[s[:2]]; ok {
+			if _, err := strconv.ParseUint(s[2:], r, 64); err == nil {
+				b = radixBound{radix: r, prefix: s[:2], digits: s[2:]}
+			}
+		}
+	}
+	if b.radix == 0 || b.radix == 10 {
+		return radixBound{}, false
+	}
+
+	n, err := strconv.ParseInt(sign+b.digits, b.radix, 64)
+	if err != nil {
+		return radixBound{}, false
+	}
+	b.n = n
+
+	return b, true
+}
+
+// parseRadixBounds accepts two numbers of the same radix. Like decimal
+// numbers, they are zero-padded if either has a leading zero, e.g.
+// "{0x00..0xff}", and the case of the digits is kept.
+func parseRadixBounds(first, last string, radix int) (RangeNode, bool) {
+	a, ok := parseRadixBound(first, radix)
+	if !ok {
+		return RangeNode{}, false
+	}
+	b, ok := parseRadixBound(last, radix)
+	if !ok {
+		return RangeNode{}, false
+	}
+	if a.radix != b.radix || !strings.EqualFold(a.prefix, b.prefix) {
+		return RangeNode{}, false
+	}
+
+	r := RangeNode{Start: a.n, End: b.n, Radix: a.radix, Prefix: a.prefix}
+
+	digits := a.digits + b.digits
+	r.Upper = strings.ToUpper(digits) == digits && strings.ToLower(digits) != digits
+
+	if zeroPadded(a.digits) || zeroPadded(b.digits) {
+		r.Width = len(a.digits)
+		if len(b.digits) > r.Width {
+			r.Width = len(b.digits)
+		}
+	}
+
+	return r, true
+}
+
+// parseCharBounds accepts a single letter as each bound. Everything in
+// between is part of the sequence, so "{X..c}" passes through the
+// punctuation between "Z" and "a" like in bash.
+func parseCharBounds(first, last string) (RangeNode, bool) {
+	start, ok := singleLetter(first)
+	if !ok {
+		return RangeNode{}, false
+	}
+	end, ok := singleLetter(last)
+	if !ok {
+		return RangeNode{}, false
+	}
+
+	// surrogate halves are not valid code points on their own:
+	lo, hi := start, end
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	if lo < 0xD800 && hi > 0xDFFF {
+		return RangeNode{}, false
+	}
+
+	return RangeNode{Start: int64(start), End: int64(end), Chars: true}, true
+}
+
+func singleLetter(s string) (rune, bool) {
+	r, w := utf8.DecodeRuneInString(s)
+	if w == 0 || w != len(s) || !unicode.IsLetter(r) {
+		return 0, false
+	}
+	return r, true
+}
+
+// zeroPadded reports whether the integer s has a leading zero, such as
+// "01" or "-01". A sign counts towards the width, but only "-" may
+// precede the zero, as in bash.
+func zeroPadded(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	return len(s) > 1 && s[0] == '0'
+}
+
+// Len returns the number of elements in the sequence.
+func (r RangeNode) Len() int64 {
+	return int64(r.span()/r.step()) + 1
+}
+
+// step returns the distance between two elements. As in bash, the sign
+// of the increment is ignored, since the bounds determine the direction,
+// and an increment of zero is treated as one.
+func (r RangeNode) step() uint64 {
+	switch {
+	case r.Incr < 0:
+		return uint64(-r.Incr)
+	case r.Incr == 0:
+		return 1
+	default:
+		return uint64(r.Incr)
+	}
+}
+
+// span returns the distance between the bounds.
+func (r RangeNode) span() uint64 {
+	if r.End < r.Start {
+		return uint64(r.Start) - uint64(r.End)
+	}
+	return uint64(r.End) - uint64(r.Start)
+}
+
+// at returns the i-th element of the sequence.
+func (r RangeNode) at(i int64) string {
+	var n int64
+	if r.End < r.Start {
+		n = int64(uint64(r.Start) - uint64(i)*r.step())
+	} else {
+		n = int64(uint64(r.Start) + uint64(i)*r.step())
+	}
+	return r.format(n)
+}
+
+// index returns i if s is the i-th element of the sequence, the
+// inverse of at.
+func (r RangeNode) index(s string) (int64, bool) {
+	var n int64
+	if r.Chars {
+		c, w := utf8.DecodeRuneInString(s)
+		switch {
+		case s == "":
+			c = '\\'
+		case w != len(s) || c == '\\':
+			return 0, false
+		}
+		n = int64(c)
+	} else {
+		digits := strings.TrimPrefix(s, "-")
+		if !strings.HasPrefix(digits, r.Prefix) {
+			return 0, false
+		}
+		radix := r.Radix
+		if radix == 0 {
+			radix = 10
+		}
+		var err error
+		n, err = strconv.ParseInt(s[:len(s)-len(digits)]+digits[len(r.Prefix):], radix, 64)
+		if err != nil || r.format(n) != s {
+			return 0, false
+		}
+	}
+
+	var dist uint64
+	switch {
+	case r.Start <= r.End && r.Start <= n && n <= r.End:
+		dist = uint64(n) - uint64(r.Start)
+	case r.End < r.Start && r.End <= n && n <= r.Start:
+		dist = uint64(r.Start) - uint64(n)
+	default:
+		return 0, false
+	}
+	if dist%r.step() != 0 {
+		return 0, false
+	}
+	return int64(dist / r.step()), true
+}
+
+// format formats n like the bounds of the sequence. Like bash, which
+// treats the backslash in "{X..c}" as an escape of nothing, it formats
+// that code point as an empty string.
+func (r RangeNode) format(n int64) string {
+	switch {
+	case r.Chars && n == '\\':
+		return ""
+	case r.Chars:
+		return string(rune(n))
+	case r.Radix != 0 && r.Radix != 10 || r.Prefix != "":
+		return r.formatRadix(n)
+	case r.Width > 0:
+		return fmt.Sprintf("%0*d", r.Width, n)
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+// formatRadix formats n with the prefix, case and padding of the
+// sequence. Unlike with decimal numbers, the sign does not count
+// towards the width.
+func (r RangeNode) formatRadix(n int64) string {
+	sign := ""
+	u := uint64(n)
+	if n < 0 {
+		sign = "-"
+		u = -u
+	}
+
+	radix := r.Radix
+	if radix == 0 {
+		radix = 10
+	}
+
+	digits := strconv.FormatUint(u, radix)
+	if r.Upper {
+		digits = strings.ToUpper(digits)
+	}
+	if len(digits) < r.Width {
+		digits = strings.Repeat("0", r.Width-len(digits)) + digits
+	}
+
+	return sign + r.Prefix + digits
+}
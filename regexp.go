@@ -0,0 +1,296 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxRegexpElements limits the number of elements of a sequence
+// expression with an increment, which have to be listed one by one.
+const maxRegexpElements = 10000
+
+// noMatch is a regular expression that matches nothing, like a phrase
+// without parts.
+const noMatch = `[^\x00-\x{10FFFF}]`
+
+// Regexp returns a regular expression that matches exactly the strings
+// the tree expands to. Use its String method to get the source.
+func (t *Tree) Regexp() (*regexp.Regexp, error) {
+	src, err := t.RegexpSource()
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(src)
+}
+
+// RegexpSource returns the source of the regular expression returned
+// by Regexp, in the syntax of package regexp. Text is quoted, lists
+// become alternations, optional lists become "(?:...)?", and sequence
+// expressions become character classes or numeric ranges. Sequence
+// expressions with an increment are listed element by element, so they
+// fail if they are too long.
+func (t *Tree) RegexpSource() (string, error) {
+	b := &regexpBuilder{opts: t.opts}
+	src := "^" + group(b.list(*t.Root, true)) + "$"
+	if b.err != nil {
+		return "", b.err
+	}
+	return src, nil
+}
+
+// regexpBuilder follows the same rules as ListNode.Expand.
+type regexpBuilder struct {
+	opts ParseOpts
+	err  error
+}
+
+func (b *regexpBuilder) node(n Node) string {
+	switch node := n.(type) {
+	case TextNode:
+		return regexp.QuoteMeta(node.Text)
+	case ListNode:
+		return b.list(node, false)
+	case PhraseNode:
+		return b.phrase(node)
+	case RangeNode:
+		return b.rangeExpr(node)
+	default:
+		panic("unexpected node type")
+	}
+}
+
+func (b *regexpBuilder) list(ln ListNode, root bool) string {
+	// empty brace expressions like "{}" are printed as regular text:
+	if len(ln.Phrases) == 0 {
+		return regexp.QuoteMeta(b.opts.OpenBrace + b.opts.CloseBrace)
+	}
+
+	if len(ln.Phrases) == 1 && !root {
+		phrase := b.phrase(ln.Phrases[0])
+		if b.opts.TreatSingleAsOptional {
+			return group(phrase) + "?"
+		}
+		return regexp.QuoteMeta(b.opts.OpenBrace) + phrase + regexp.QuoteMeta(b.opts.CloseBrace)
+	}
+
+	alternatives := make([]string, len(ln.Phrases))
+	for i, phrase := range ln.Phrases {
+		alternatives[i] = b.phrase(phrase)
+	}
+	return alternation(alternatives)
+}
+
+func (b *regexpBuilder) phrase(pn PhraseNode) string {
+	// a phrase without parts expands to nothing at all:
+	if len(pn.Parts) == 0 {
+		return noMatch
+	}
+
+	src := ""
+	for _, part := range pn.Parts {
+		src += b.node(part)
+	}
+	return src
+}
+
+func (b *regexpBuilder) rangeExpr(r RangeNode) string {
+	if r.step() != 1 {
+		if r.Len() > maxRegexpElements {
+			if b.err == nil {
+				b.err = fmt.Errorf("sequence expression with %d elements is too long for a regular expression", r.Len())
+			}
+			return ""
+		}
+		elements := make([]string, r.Len())
+		for i := range elements {
+			elements[i] = regexp.QuoteMeta(r.at(int64(i)))
+		}
+		return alternation(elements)
+	}
+
+	lo, hi := r.Start, r.End
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+
+	if r.Chars {
+		if lo > '\\' || hi < '\\' {
+			return fmt.Sprintf(`[\x{%x}-\x{%x}]`, lo, hi)
+		}
+
+		// the backslash is formatted as an empty string, see
+		// RangeNode.format, and the bounds are letters around it:
+		return fmt.Sprintf(`[\x{%x}-\x{%x}\x{%x}-\x{%x}]?`, lo, '\\'-1, '\\'+1, hi)
+	}
+
+	radix, prefix := 10, ""
+	if r.Radix != 0 && r.Radix != 10 || r.Prefix != "" {
+		radix, prefix = r.Radix, regexp.QuoteMeta(r.Prefix)
+		if radix == 0 {
+			radix = 10
+		}
+	}
+
+	// the minus sign counts towards the width of decimal numbers only:
+	negWidth := r.Width
+	if prefix == "" && radix == 10 && negWidth > 0 {
+		negWidth--
+	}
+
+	alternatives := []string{}
+	if lo < 0 {
+		top := hi
+		if top >= 0 {
+			top = -1
+		}
+		alternatives = append(alternatives, "-"+prefix+numberRange(magnitude(top), magnitude(lo), negWidth, radix, r.Upper))
+	}
+	if hi >= 0 {
+		bottom := lo
+		if bottom < 0 {
+			bottom = 0
+		}
+		alternatives = append(alternatives, prefix+numberRange(uint64(bottom), uint64(hi), r.Width, radix, r.Upper))
+	}
+	return alternation(alternatives)
+}
+
+// magnitude returns the absolute value of the negative number n.
+func magnitude(n int64) uint64 {
+	return uint64(-(n + 1)) + 1
+}
+
+// numberRange returns a regular expression for the numbers from lo to
+// hi in the given radix, zero-padded to width. Numbers with the same
+// count of digits are matched digit by digit.
+func numberRange(lo, hi uint64, width, radix int, upper bool) string {
+	digits := "0123456789abcdefghijklmnopqrstuvwxyz"[:radix]
+	format := func(n uint64) string {
+		s := strconv.FormatUint(n, radix)
+		if upper {
+			s = strings.ToUpper(s)
+		}
+		return s
+	}
+	if upper {
+		digits = strings.ToUpper(digits)
+	}
+
+	alternatives := []string{}
+	for {
+		a := format(lo)
+		top := hi
+		if max, ok := maxWithDigits(len(a), radix); ok && max < hi {
+			top = max
+		}
+
+		padding := ""
+		if width > len(a) {
+			padding = strings.Repeat("0", width-len(a))
+		}
+		alternatives = append(alternatives, padding+digitRange(a, format(top), digits))
+
+		if top == hi {
+			break
+		}
+		lo = top + 1
+	}
+	return alternation(alternatives)
+}
+
+// maxWithDigits returns the largest number with n digits in the given
+// radix, or false if it does not fit into an uint64.
+func maxWithDigits(n, radix int) (uint64, bool) {
+	p := uint64(1)
+	for i := 0; i < n; i++ {
+		if p > math.MaxUint64/uint64(radix) {
+			return 0, false
+		}
+		p *= uint64(radix)
+	}
+	return p - 1, true
+}
+
+// digitRange returns a regular expression for the numbers from a to b,
+// which have the same count of digits.
+func digitRange(a, b, digits string) string {
+	if a == b {
+		return a
+	}
+	if a[0] == b[0] {
+		return a[:1] + digitRange(a[1:], b[1:], digits)
+	}
+
+	first, last := digits[:1], digits[len(digits)-1:]
+	lo, hi := strings.IndexByte(digits, a[0]), strings.IndexByte(digits, b[0])
+	rest := ""
+	switch n := len(a) - 1; n {
+	case 0:
+	case 1:
+		rest = digitClass(digits, 0, len(digits)-1)
+	default:
+		rest = digitClass(digits, 0, len(digits)-1) + "{" + strconv.Itoa(n) + "}"
+	}
+
+	// the numbers that start with the first or last digit are matched
+	// separately, unless they are all there is to them:
+	alternatives := []string{}
+	if a[1:] != strings.Repeat(first, len(a)-1) {
+		alternatives = append(alternatives, a[:1]+digitRange(a[1:], strings.Repeat(last, len(a)-1), digits))
+		lo++
+	}
+	var end string
+	if b[1:] != strings.Repeat(last, len(b)-1) {
+		end = b[:1] + digitRange(strings.Repeat(first, len(b)-1), b[1:], digits)
+		hi--
+	}
+	if lo <= hi {
+		alternatives = append(alternatives, digitClass(digits, lo, hi)+rest)
+	}
+	if end != "" {
+		alternatives = append(alternatives, end)
+	}
+	return alternation(alternatives)
+}
+
+// digitClass returns a character class for the digits from index lo to
+// hi, like "[0-9a-f]".
+func digitClass(digits string, lo, hi int) string {
+	if lo == hi {
+		return digits[lo : lo+1]
+	}
+
+	class := "["
+	for i := lo; i <= hi; {
+		j := i
+		for j < hi && digits[j+1] == digits[j]+1 {
+			j++
+		}
+		switch j - i {
+		case 0:
+			class += digits[i : i+1]
+		case 1:
+			class += digits[i : j+1]
+		default:
+			class += digits[i:i+1] + "-" + digits[j:j+1]
+		}
+		i = j + 1
+	}
+	return class + "]"
+}
+
+// alternation returns a group that matches any of the alternatives.
+func alternation(alternatives []string) string {
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return "(?:" + strings.Join(alternatives, "|") + ")"
+}
+
+// group makes src safe to be followed by other expressions.
+func group(src string) string {
+	return "(?:" + src + ")"
+}
@@ -0,0 +1,255 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestRegexp(t *testing.T) {
+	testRegexp(t, expandTests, parse)
+	testRegexp(t, quoteTests, parseQuotes)
+	testRegexp(t, rangeTests, parse)
+	testRegexp(t, charRangeTests, parse)
+	testRegexp(t, radixRangeTests, parse)
+	testRegexp(t, hexRangeTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeRadix = 16
+		return New().ParseCustom(input, opts)
+	})
+	testRegexp(t, rangeWidthTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.RangeWidth = 3
+		return New().ParseCustom(input, opts)
+	})
+	testRegexp(t, lenientTests, func(input string) (*Tree, error) {
+		opts := BashOpts()
+		opts.Lenient = true
+		return New().ParseCustom(input, opts)
+	})
+	testRegexp(t, expandTestsCustom, parseCustom)
+}
+
+// testRegexp checks that the regexp matches the strings of the
+// expansion, and strings close to them only if they are part of the
+// expansion.
+func testRegexp(t *testing.T, tests []expandTest, f parseFunc) {
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			tree, err := f(test.input)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			re, err := tree.Regexp()
+			if err != nil {
+				t.Fatalf("Regexp error: %v", err)
+			}
+
+			expansion := map[string]bool{}
+			for _, s := range test.output {
+				expansion[s] = true
+			}
+
+			candidates := []string{"", "x", "{}", "()"}
+			for _, s := range test.output {
+				candidates = append(candidates, s, s+"x", "x"+s, s+s, s+"\n")
+				if s != "" {
+					candidates = append(candidates, s[1:], s[:len(s)-1])
+				}
+			}
+
+			for _, s := range candidates {
+				if have := re.MatchString(s); have != expansion[s] {
+					t.Errorf("%q: want %t, have %t (%s)", s, expansion[s], have, re)
+				}
+			}
+		})
+	}
+}
+
+func TestRegexpSource(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	sourceTests := []struct {
+		input string
+		opts  ParseOpts
+		src   string
+	}{
+		{"a.b", BashOpts(), `^(?:a\.b)$`},
+		{"foo-{a,b}{}", BashOpts(), `^(?:foo-(?:a|b)\{\})$`},
+		{"{a}", BashOpts(), `^(?:\{a\})$`},
+		{"x(a)y,z", customOpts, `^(?:(?:x(?:a)?y|z))$`},
+		{"{1..10}", BashOpts(), `^(?:(?:[1-9]|10))$`},
+		{"{007..123}", BashOpts(), `^(?:(?:00[7-9]|0[1-9][0-9]|1(?:[01][0-9]|2[0-3])))$`},
+		{"{-2..2}", BashOpts(), `^(?:(?:-[12]|[0-2]))$`},
+		{"{0x08..0x1F}", BashOpts(), `^(?:0x(?:0[89A-F]|1[0-9A-F]))$`},
+		{"{a..e}", BashOpts(), `^(?:[\x{61}-\x{65}])$`},
+		{"{1..10..4}", BashOpts(), `^(?:(?:1|5|9))$`},
+	}
+
+	for _, st := range sourceTests {
+		tree, err := New().ParseCustom(st.input, st.opts)
+		if err != nil {
+			t.Fatalf("%q: Parse error: %v", st.input, err)
+		}
+		src, err := tree.RegexpSource()
+		if err != nil {
+			t.Fatalf("%q: RegexpSource error: %v", st.input, err)
+		}
+		if src != st.src {
+			t.Errorf("%q: want %s, have %s", st.input, st.src, src)
+		}
+	}
+}
+
+func TestRegexpLarge(t *testing.T) {
+	tree, err := parse("x{1..1000000..2}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if _, err := tree.Regexp(); err == nil {
+		t.Error("Expected an error")
+	}
+
+	tree, err = parse("{-9223372036854775808..-2},{1..9223372036854775807}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	re, err := tree.Regexp()
+	if err != nil {
+		t.Fatalf("Regexp error: %v", err)
+	}
+	for _, s := range []string{"-9223372036854775808,1", "-2,9223372036854775807", "-1000,1000"} {
+		if !re.MatchString(s) {
+			t.Errorf("Expected %q to match", s)
+		}
+	}
+	for _, s := range []string{"-9223372036854775809,1", "-2,9223372036854775808", "-1,1", "-2,0", "-0,1"} {
+		if re.MatchString(s) {
+			t.Errorf("Expected %q not to match", s)
+		}
+	}
+}
+
+// TestRegexpRangeProperties checks on random sequence expressions that
+// the regexp matches the same numbers as the sequence contains.
+func TestRegexpRangeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	bound := func() int64 {
+		return int64(r.Intn(600) - 300)
+	}
+
+	n := 100
+	if testing.Short() {
+		n = 10
+	}
+	for i := 0; i < n; i++ {
+		start, end, width := bound(), bound(), r.Intn(6)
+		incr := ""
+		if r.Intn(4) == 0 {
+			incr = ".." + strconv.Itoa(r.Intn(20)-10)
+		}
+
+		var input string
+		if r.Intn(3) == 0 {
+			input = fmt.Sprintf("{%s0x%0*x..0x%x%s}", sign(start), width, abs(start), end, incr)
+			if end < 0 {
+				input = fmt.Sprintf("{%s0x%0*x..-0x%x%s}", sign(start), width, abs(start), -end, incr)
+			}
+		} else {
+			input = fmt.Sprintf("{%0*d..%d%s}", width, start, end, incr)
+		}
+
+		tree, err := parse(input)
+		if err != nil {
+			t.Fatalf("%q: Parse error: %v", input, err)
+		}
+		re, err := tree.Regexp()
+		if err != nil {
+			t.Fatalf("%q: Regexp error: %v", input, err)
+		}
+		rn := tree.Root.Phrases[0].Parts[0].(RangeNode)
+
+		lo, hi := start, end
+		if hi < lo {
+			lo, hi = hi, lo
+		}
+		for n := lo - 20; n <= hi+20; n++ {
+			candidates := []string{strconv.FormatInt(n, 10), fmt.Sprintf("0x%x", n), fmt.Sprintf("%s0x%0*x", sign(n), width, abs(n))}
+			for w := 1; w <= 5; w++ {
+				candidates = append(candidates, fmt.Sprintf("%0*d", w, n))
+			}
+			for _, s := range candidates {
+				if _, want := rn.index(s); re.MatchString(s) != want {
+					t.Fatalf("%q: %q: want %t, have %t (%s)", input, s, want, !want, re)
+				}
+			}
+		}
+	}
+}
+
+func sign(n int64) string {
+	if n < 0 {
+		return "-"
+	}
+	return ""
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// TestRegexpProperties checks on random patterns that the regexp
+// matches the expansion, and other strings just like Match does.
+func TestRegexpProperties(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	n := 300
+	if testing.Short() {
+		n = 30
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, opts := range []ParseOpts{BashOpts(), customOpts} {
+		for i := 0; i < n; i++ {
+			input := randomPattern(r, opts, 3)
+			if opts.TreatRootAsList && r.Intn(2) == 0 {
+				input += opts.Separator + randomPattern(r, opts, 3)
+			}
+
+			tree, err := New().ParseCustom(input, opts)
+			if err != nil {
+				t.Fatalf("%q: Parse error: %v", input, err)
+			}
+			re, err := tree.Regexp()
+			if err != nil {
+				t.Fatalf("%q: Regexp error: %v", input, err)
+			}
+
+			// Match is much slower than the regexp, so only strings close
+			// to the first few are compared:
+			candidates := []string{"", "{}", "()"}
+			for j, s := range tree.Expand() {
+				if !re.MatchString(s) {
+					t.Errorf("%q: Expected %q to match (%s)", input, s, re)
+				}
+				if j >= 10 {
+					continue
+				}
+				candidates = append(candidates, s+"a", "b"+s, s+s)
+				if s != "" {
+					candidates = append(candidates, s[1:], s[:len(s)-1])
+				}
+			}
+			for _, s := range candidates {
+				if want, have := tree.Match(s), re.MatchString(s); have != want {
+					t.Errorf("%q: %q: want %t, have %t (%s)", input, s, want, have, re)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SimplifyOpts control the changes made by Simplify.
+type SimplifyOpts struct {
+	// Dedupe removes alternatives that are the same as an earlier one in
+	// the same list, so "{a,b,a}" becomes "{a,b}". This drops the strings
+	// they expand to, but keeps the order of the others.
+	Dedupe bool
+}
+
+// Simplify returns a copy of the tree without redundant structure,
+// which expands to the same strings in the same order, unless Dedupe
+// is set:
+//
+//   - lists that make up a whole alternative are spliced into the
+//     surrounding list, so "{a,{b,c}}" becomes "{a,b,c}"
+//   - lists and sequences that expand to a single string become text,
+//     like "{a}", which expands to "{a}" unless TreatSingleAsOptional
+//     is set, or "{1..1}"
+//   - adjacent text in a phrase is merged into one TextNode
+func (t *Tree) Simplify(opts SimplifyOpts) *Tree {
+	s := &simplifier{opts: opts, tree: &Tree{opts: t.opts}}
+	root := s.list(*t.Root, true)
+	s.tree.Root = &root
+	return s.tree
+}
+
+type simplifier struct {
+	opts SimplifyOpts
+	tree *Tree // the copy
+}
+
+func (s *simplifier) list(ln ListNode, root bool) ListNode {
+	phrases := make([]PhraseNode, 0, len(ln.Phrases))
+	for _, phrase := range ln.Phrases {
+		phrases = append(phrases, s.phrase(phrase))
+	}
+
+	if len(phrases) > 1 || root && s.tree.opts.TreatRootAsList {
+		phrases = flatten(phrases, s.tree.opts)
+	}
+	if s.opts.Dedupe {
+		phrases = dedupe(phrases)
+	}
+
+	ln.Phrases = phrases
+	ln.Tree = s.tree
+	return ln
+}
+
+// listPart simplifies a list in a phrase and returns the parts that
+// take its place.
+func (s *simplifier) listPart(ln ListNode) []Node {
+	union := len(ln.Phrases) > 1
+	ln = s.list(ln, false)
+
+	// without its duplicates, a single alternative is left, which must
+	// not be printed in braces or become optional:
+	if union && len(ln.Phrases) == 1 {
+		return ln.Phrases[0].Parts
+	}
+
+	return []Node{fold(ln)}
+}
+
+func (s *simplifier) phrase(pn PhraseNode) PhraseNode {
+	parts := make([]Node, 0, len(pn.Parts))
+	for _, part := range pn.Parts {
+		if ln, ok := part.(ListNode); ok {
+			parts = append(parts, s.listPart(ln)...)
+		} else {
+			parts = append(parts, fold(part))
+		}
+	}
+
+	pn.Parts = mergeText(parts)
+	return pn
+}
+
+// fold turns a node that expands to a single string into text.
+func fold(n Node) Node {
+	if countPart(n).Cmp(big.NewInt(1)) != 0 {
+		return n
+	}
+
+	var text string
+	switch node := n.(type) {
+	case TextNode:
+		return node
+	case ListNode:
+		text = node.Expand(false)[0]
+	case RangeNode:
+		text = node.Expand()[0]
+	}
+	return TextNode{Span: n.Position(), Text: text}
+}
+
+// mergeText merges adjacent text and drops empty text, unless nothing
+// else is left, since a phrase without parts expands to nothing at all.
+func mergeText(parts []Node) []Node {
+	merged := make([]Node, 0, len(parts))
+	for _, part := range parts {
+		text, ok := part.(TextNode)
+		if !ok {
+			merged = append(merged, part)
+			continue
+		}
+		if text.Text == "" && len(parts) > 1 {
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 {
+			if prev, ok := merged[last].(TextNode); ok {
+				prev.Text += text.Text
+				prev.EndPos = text.EndPos
+				merged[last] = prev
+				continue
+			}
+		}
+		merged = append(merged, text)
+	}
+
+	if len(merged) == 0 && len(parts) > 0 {
+		empty := parts[0].Position()
+		merged = append(merged, TextNode{Span: Span{empty.Pos, empty.Pos}})
+	}
+	return merged
+}
+
+// flatten splices lists that are the only part of a phrase into the
+// surrounding list of phrases, which expands to the same strings in the
+// same order. The surrounding list must not be affected by the number
+// of its phrases, so it has to have more than one, or be the root.
+func flatten(phrases []PhraseNode, opts ParseOpts) []PhraseNode {
+	flat := make([]PhraseNode, 0, len(phrases))
+	for _, phrase := range phrases {
+		ln, ok := soleList(phrase)
+		switch {
+		case ok && len(ln.Phrases) > 1:
+			flat = append(flat, flatten(ln.Phrases, opts)...)
+		case ok && len(ln.Phrases) == 1 && opts.TreatSingleAsOptional:
+			flat = append(flat, flatten(ln.Phrases, opts)...)
+			flat = append(flat, PhraseNode{Parts: []Node{TextNode{}}})
+		default:
+			flat = append(flat, phrase)
+		}
+	}
+	return flat
+}
+
+// soleList returns the list that makes up the whole phrase, if any.
+func soleList(phrase PhraseNode) (ListNode, bool) {
+	if len(phrase.Parts) != 1 {
+		return ListNode{}, false
+	}
+	ln, ok := phrase.Parts[0].(ListNode)
+	return ln, ok
+}
+
+// dedupe removes phrases that are the same as an earlier one.
+func dedupe(phrases []PhraseNode) []PhraseNode {
+	seen := map[string]bool{}
+	unique := make([]PhraseNode, 0, len(phrases))
+	for _, phrase := range phrases {
+		k := key(phrase)
+		if !seen[k] {
+			seen[k] = true
+			unique = append(unique, phrase)
+		}
+	}
+	return unique
+}
+
+// key identifies a node by its structure, regardless of its span.
+func key(n Node) string {
+	switch node := n.(type) {
+	case ListNode:
+		keys := make([]string, len(node.Phrases))
+		for i, phrase := range node.Phrases {
+			keys[i] = key(phrase)
+		}
+		return "{" + strings.Join(keys, ",") + "}"
+	case PhraseNode:
+		keys := make([]string, len(node.Parts))
+		for i, part := range node.Parts {
+			keys[i] = key(part)
+		}
+		return "(" + strings.Join(keys, " ") + ")"
+	case TextNode:
+		return strconv.Quote(node.Text)
+	case RangeNode:
+		return fmt.Sprintf("%d..%d..%d/%d/%t/%d/%q/%t", node.Start, node.End, node.Incr, node.Width, node.Chars, node.Radix, node.Prefix, node.Upper)
+	default:
+		panic("unexpected node type")
+	}
+}
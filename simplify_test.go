@@ -0,0 +1,155 @@
+package braceexpansion
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/thomasheller/slicecmp"
+)
+
+func TestSimplify(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	simplifyTests := []struct {
+		input  string
+		opts   ParseOpts
+		dedupe bool
+		output string
+	}{
+		{"{a,{b,c}}", BashOpts(), false, "{a,b,c}"},
+		{"{a,{b,{c,d}}}e", BashOpts(), false, "{a,b,c,d}e"},
+		{"{{a,b}}", BashOpts(), false, "{{a,b}}"},
+		{"x{y}", BashOpts(), false, `x\{y\}`},
+		{"{a,{b}}", BashOpts(), false, `{a,\{b\}}`},
+		{"{1..1}{a}z", BashOpts(), false, `1\{a\}z`},
+		{"a{b,c}{}", BashOpts(), false, `a{b,c}\{\}`},
+		{"{a,,b}{,}", BashOpts(), false, "{a,,b}{,}"},
+		{"{a,a,b}", BashOpts(), false, "{a,a,b}"},
+		{"{a,a,b}", BashOpts(), true, "{a,b}"},
+		{"x{a,a}y", BashOpts(), true, "xay"},
+		{"{a,{b,a}}{,}", BashOpts(), true, "{a,b}"},
+		{"{a{1..2},a{1..2},a{1..3}}", BashOpts(), true, "{a{1..2},a{1..3}}"},
+		{"x(y)", customOpts, false, "x(y)"},
+		{"(a,(b))", customOpts, false, "a,b,"},
+		{"x(a,(b))", customOpts, false, "x(a,b,)"},
+		{"(a,a),a", customOpts, true, "a"},
+		{"x(a,(a))", customOpts, true, "x(a,)"},
+	}
+
+	for _, st := range simplifyTests {
+		t.Run(st.input, func(t *testing.T) {
+			tree, err := New().ParseCustom(st.input, st.opts)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			simple := tree.Simplify(SimplifyOpts{Dedupe: st.dedupe})
+			if have, err := simple.Format(); err != nil || have != st.output {
+				t.Errorf("want %q, have %q (%v)", st.output, have, err)
+			}
+		})
+	}
+}
+
+func TestSimplifyKeepsTree(t *testing.T) {
+	tree, err := parse("{a,{b,c}}x{y}")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	before := key(*tree.Root)
+
+	tree.Simplify(SimplifyOpts{Dedupe: true})
+	if after := key(*tree.Root); after != before {
+		t.Errorf("Simplify changed the tree from %s to %s", before, after)
+	}
+}
+
+// randomPattern returns a phrase of up to three parts, where lists are
+// nested up to depth.
+func randomPattern(r *rand.Rand, opts ParseOpts, depth int) string {
+	s := ""
+	for i := r.Intn(4); i > 0; i-- {
+		switch n := r.Intn(8); {
+		case n < 3:
+			s += []string{"a", "b", ""}[n]
+		case n == 3 && opts.Ranges:
+			s += []string{"{1..2}", "{2..2}", "{a..b}"}[r.Intn(3)]
+		case depth > 0:
+			s += opts.OpenBrace
+			for j := r.Intn(4); j > 0; j-- {
+				s += randomPattern(r, opts, depth-1)
+				if j > 1 {
+					s += opts.Separator
+				}
+			}
+			s += opts.CloseBrace
+		}
+	}
+	return s
+}
+
+// TestSimplifyProperties checks on random patterns that Simplify keeps
+// the expansion, or with Dedupe its set of strings and their order, and
+// that simplifying again changes nothing.
+func TestSimplifyProperties(t *testing.T) {
+	customOpts := ParseOpts{OpenBrace: "(", CloseBrace: ")", Separator: ",", TreatRootAsList: true, TreatSingleAsOptional: true}
+
+	r := rand.New(rand.NewSource(1))
+	for _, opts := range []ParseOpts{BashOpts(), customOpts} {
+		for i := 0; i < 1000; i++ {
+			input := randomPattern(r, opts, 3)
+			if opts.TreatRootAsList && r.Intn(2) == 0 {
+				input += opts.Separator + randomPattern(r, opts, 3)
+			}
+
+			tree, err := New().ParseCustom(input, opts)
+			if err != nil {
+				t.Fatalf("%q: Parse error: %v", input, err)
+			}
+			want := tree.Expand()
+
+			simple := tree.Simplify(SimplifyOpts{})
+			if have := simple.Expand(); !slicecmp.Equal(want, have) {
+				t.Errorf("%q: Unexpected output:\n%s", input, slicecmp.Sprint([]string{"want", "have"}, want, have))
+			}
+			if again := simple.Simplify(SimplifyOpts{}); key(*again.Root) != key(*simple.Root) {
+				t.Errorf("%q: Simplify is not idempotent: %s, then %s", input, key(*simple.Root), key(*again.Root))
+			}
+
+			deduped := tree.Simplify(SimplifyOpts{Dedupe: true})
+			have := deduped.Expand()
+			if !sameSet(want, have) || !subsequence(have, want) {
+				t.Errorf("%q: Unexpected output with Dedupe:\n%s", input, slicecmp.Sprint([]string{"want", "have"}, want, have))
+			}
+			if again := deduped.Simplify(SimplifyOpts{Dedupe: true}); key(*again.Root) != key(*deduped.Root) {
+				t.Errorf("%q: Simplify is not idempotent: %s, then %s", input, key(*deduped.Root), key(*again.Root))
+			}
+		}
+	}
+}
+
+func sameSet(a, b []string) bool {
+	set := map[string]int{}
+	for _, s := range a {
+		set[s] |= 1
+	}
+	for _, s := range b {
+		set[s] |= 2
+	}
+	for _, v := range set {
+		if v != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// subsequence reports whether a can be made from b by dropping strings.
+func subsequence(a, b []string) bool {
+	for _, s := range b {
+		if len(a) > 0 && a[0] == s {
+			a = a[1:]
+		}
+	}
+	return len(a) == 0
+}
@@ -0,0 +1,60 @@
+package braceexpansion
+
+import "fmt"
+
+// A Visitor's Visit method is called by Walk for each node. If it
+// returns a Visitor w other than nil, Walk visits each child of the node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: it calls v.Visit(node),
+// then walks the phrases of a ListNode or the parts of a PhraseNode.
+// To walk a parsed tree, pass tree.Root: a *ListNode is walked like the
+// ListNode it points to, so visitors only ever see ListNode values.
+func Walk(v Visitor, node Node) {
+	if root, ok := node.(*ListNode); ok {
+		if root == nil {
+			return
+		}
+		node = *root
+	}
+
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case ListNode:
+		for _, phrase := range n.Phrases {
+			Walk(v, phrase)
+		}
+	case PhraseNode:
+		for _, part := range n.Parts {
+			Walk(v, part)
+		}
+	case TextNode, RangeNode:
+		// nothing to do
+	default:
+		panic(fmt.Sprintf("braceexpansion.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order like Walk: it calls
+// f(node), and if that returns true, Inspect visits each child of the
+// node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,157 @@
+package braceexpansion
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	tree, err := parse("a{b,{1..3}}c")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var visited []string
+	Inspect(*tree.Root, func(n Node) bool {
+		switch node := n.(type) {
+		case nil:
+			visited = append(visited, ")")
+		case ListNode:
+			visited = append(visited, "List(")
+		case PhraseNode:
+			visited = append(visited, "Phrase(")
+		case TextNode:
+			visited = append(visited, fmt.Sprintf("%q(", node.Text))
+		case RangeNode:
+			visited = append(visited, fmt.Sprintf("%d..%d(", node.Start, node.End))
+		}
+		return true
+	})
+
+	want := `List( Phrase( "a"( ) List( Phrase( "b"( ) ) Phrase( 1..3( ) ) ) "c"( ) ) )`
+	if have := strings.Join(visited, " "); have != want {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestInspectRoot(t *testing.T) {
+	tree, err := parse("a{b,c}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var byValue, byPointer []Node
+	Inspect(*tree.Root, func(n Node) bool {
+		byValue = append(byValue, n)
+		return true
+	})
+	Inspect(tree.Root, func(n Node) bool {
+		byPointer = append(byPointer, n)
+		return true
+	})
+
+	if len(byValue) == 0 || fmt.Sprint(byPointer) != fmt.Sprint(byValue) {
+		t.Errorf("want %v, have %v", byValue, byPointer)
+	}
+	if _, ok := byPointer[0].(ListNode); !ok {
+		t.Errorf("Expected a ListNode, got %T", byPointer[0])
+	}
+
+	Inspect((*ListNode)(nil), func(n Node) bool {
+		t.Errorf("Unexpected node %v", n)
+		return true
+	})
+}
+
+func TestInspectSkip(t *testing.T) {
+	tree, err := parse("a{b,c{d,e}}f")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// don't descend into lists below the root:
+	var texts []string
+	Inspect(*tree.Root, func(n Node) bool {
+		if text, ok := n.(TextNode); ok {
+			texts = append(texts, text.Text)
+		}
+		_, isList := n.(ListNode)
+		return !isList || n.Position() == tree.Root.Span
+	})
+
+	if have := strings.Join(texts, " "); have != "a f" {
+		t.Errorf("want %q, have %q", "a f", have)
+	}
+}
+
+func TestNodeType(t *testing.T) {
+	for _, input := range []string{"a{b,,{1..3}}{}c", "{,}", ""} {
+		tree, err := parse(input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		Inspect(*tree.Root, func(n Node) bool {
+			var want NodeType
+			switch n.(type) {
+			case nil:
+				return true
+			case ListNode:
+				want = NodeList
+			case PhraseNode:
+				want = NodePhrase
+			case TextNode:
+				want = NodeText
+			case RangeNode:
+				want = NodeRange
+			}
+			if n.Type() != want {
+				t.Errorf("%q: want %d for %T, have %d", input, want, n, n.Type())
+			}
+			return true
+		})
+	}
+
+	// nodes built by rewriters have their type without setting it:
+	for want, n := range map[NodeType]Node{NodeList: ListNode{}, NodePhrase: PhraseNode{}, NodeText: TextNode{Text: "x"}, NodeRange: RangeNode{}} {
+		if n.Type() != want {
+			t.Errorf("want %d for %T, have %d", want, n, n.Type())
+		}
+	}
+}
+
+func TestNewList(t *testing.T) {
+	tree, err := parse("x{a,b}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// rewrite "x{a,b}" to "x{a,b}{1,2}":
+	phrase := func(s string) PhraseNode {
+		return PhraseNode{Parts: []Node{TextNode{Text: s}}}
+	}
+	root := &tree.Root.Phrases[0]
+	root.Parts = append(root.Parts, tree.NewList(phrase("1"), phrase("2")))
+
+	want := []string{"xa1", "xa2", "xb1", "xb2"}
+	if have := tree.Expand(); !reflect.DeepEqual(have, want) {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !tree.Match("xb2") {
+		t.Error("Expected a match")
+	}
+	if have, err := tree.Format(); err != nil || have != "x{a,b}{1,2}" {
+		t.Errorf("want %q, have %q (%v)", "x{a,b}{1,2}", have, err)
+	}
+
+	// a list without a tree has no options to expand with:
+	root.Parts[2] = ListNode{Phrases: []PhraseNode{phrase("1")}}
+	defer func() {
+		if e := recover(); e == nil || !strings.Contains(fmt.Sprint(e), "Tree.NewList") {
+			t.Errorf("Expected a panic that mentions Tree.NewList, got %v", e)
+		}
+	}()
+	tree.Expand()
+}